@@ -0,0 +1,135 @@
+package grpc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc"
+)
+
+// accessTokenValidation adds the checks for access tokens which the ID token verifier of go-oidc does not cover
+type accessTokenValidation struct {
+	audiences         []string
+	authorizedParties []string
+	scopes            []string
+	signingAlgorithms []string
+	clockSkew         time.Duration
+	tokenType         string
+}
+
+func newAccessTokenValidation(cfg oidcConfig) (*accessTokenValidation, error) {
+	clockSkew, err := duration("clockSkew", cfg.ClockSkew)
+	if err != nil {
+		return nil, err
+	}
+
+	audiences := cfg.Audiences
+	if !cfg.SkipClientIDCheck {
+		if cfg.ClientID == "" {
+			return nil, errors.New("clientID is required unless skipClientIDCheck is set")
+		}
+		audiences = append([]string{cfg.ClientID}, audiences...)
+	}
+
+	return &accessTokenValidation{
+		audiences:         audiences,
+		authorizedParties: cfg.AuthorizedParties,
+		scopes:            cfg.Scopes,
+		signingAlgorithms: cfg.SigningAlgorithms,
+		clockSkew:         clockSkew,
+		tokenType:         cfg.TokenType,
+	}, nil
+}
+
+// verifierConfig leaves audience and expiry to check, go-oidc supports neither multiple audiences nor a clock skew
+func (v *accessTokenValidation) verifierConfig() *oidc.Config {
+	return &oidc.Config{
+		SkipClientIDCheck:    true,
+		SkipExpiryCheck:      true,
+		SupportedSigningAlgs: v.signingAlgorithms,
+	}
+}
+
+func (v *accessTokenValidation) check(token *oidc.IDToken, rawToken string) error {
+	var claims struct {
+		NotBefore *json.Number `json:"nbf"`
+		Azp       string       `json:"azp"`
+		Typ       string       `json:"typ"`
+	}
+	if err := token.Claims(&claims); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if token.Expiry.IsZero() || now.After(token.Expiry.Add(v.clockSkew)) {
+		return identificationError(IdentificationExpired, "token is expired (expiry: %v)", token.Expiry)
+	}
+	if claims.NotBefore != nil {
+		nbf, err := claims.NotBefore.Float64()
+		if err != nil {
+			return identificationError(IdentificationMalformed, "invalid nbf claim: %w", err)
+		}
+		if notBefore := time.Unix(int64(nbf), 0); now.Add(v.clockSkew).Before(notBefore) {
+			return identificationError(IdentificationNotYetValid, "token is not valid yet (nbf: %v)", notBefore)
+		}
+	}
+
+	if len(v.audiences) > 0 && !containsAny(token.Audience, v.audiences) {
+		return identificationError(IdentificationWrongAudience, "expected audience in %q, got %q", v.audiences, token.Audience)
+	}
+
+	if len(v.authorizedParties) > 0 && !contains(v.authorizedParties, claims.Azp) {
+		return identificationError(IdentificationWrongAudience, "unexpected authorized party %q", claims.Azp)
+	}
+
+	if v.tokenType != "" && !sameTokenType(v.tokenType, headerType(rawToken)) && !sameTokenType(v.tokenType, claims.Typ) {
+		return identificationError(IdentificationInsufficient, "expected token type %q", v.tokenType)
+	}
+
+	if len(v.scopes) > 0 {
+		scopes, err := TokenScopes(&oauth2Identity{token: token})
+		if err != nil {
+			return err
+		}
+		if !containsAll(scopes, v.scopes) {
+			return identificationError(IdentificationInsufficient, "expected scopes %q, got %q", v.scopes, scopes)
+		}
+	}
+
+	return nil
+}
+
+// headerType returns the typ header of a JWT in compact serialization
+func headerType(rawToken string) string {
+	parts := strings.SplitN(rawToken, ".", 2)
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return ""
+	}
+
+	var header struct {
+		Typ string `json:"typ"`
+	}
+	_ = json.Unmarshal(data, &header)
+	return header.Typ
+}
+
+// sameTokenType compares media types case insensitive, the application/ prefix is optional (RFC 7515)
+func sameTokenType(expected, actual string) bool {
+	normalize := func(typ string) string {
+		return strings.TrimPrefix(strings.ToLower(typ), "application/")
+	}
+	return actual != "" && normalize(expected) == normalize(actual)
+}
+
+func containsAny(values []string, candidates []string) bool {
+	for _, candidate := range candidates {
+		if contains(values, candidate) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package grpc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-oidc"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// staticKeySet verifies tokens with a fixed public key, go-oidc v2 has no static key set
+type staticKeySet struct {
+	key crypto.PublicKey
+}
+
+func (keySet *staticKeySet) VerifySignature(_ context.Context, rawToken string) ([]byte, error) {
+	jws, err := jose.ParseSigned(rawToken)
+	if err != nil {
+		return nil, err
+	}
+	return jws.Verify(keySet.key)
+}
+
+func signAccessToken(t *testing.T, key *rsa.PrivateKey, typ string, claims map[string]interface{}) string {
+	t.Helper()
+
+	options := new(jose.SignerOptions)
+	if typ != "" {
+		options = options.WithType(jose.ContentType(typ))
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jws.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestAccessTokenValidation_check(t *testing.T) {
+	const issuer = "https://keycloak.example.com/auth/realms/shop"
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keySet := &staticKeySet{key: &key.PublicKey}
+
+	now := time.Now()
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		claims := map[string]interface{}{
+			"iss":   issuer,
+			"sub":   "user",
+			"aud":   "api",
+			"azp":   "frontend",
+			"exp":   now.Add(time.Hour).Unix(),
+			"scope": "openid orders",
+		}
+		for name, value := range overrides {
+			if value == nil {
+				delete(claims, name)
+				continue
+			}
+			claims[name] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name   string
+		config oidcConfig
+		typ    string
+		claims map[string]interface{}
+		want   IdentificationStatus
+	}{
+		{name: "valid", config: oidcConfig{ClientID: "api"}, claims: claims(nil), want: IdentificationOK},
+		{name: "client id in audience list", config: oidcConfig{ClientID: "api"}, claims: claims(map[string]interface{}{"aud": []string{"account", "api"}}), want: IdentificationOK},
+		{name: "client id not in audience", config: oidcConfig{ClientID: "api"}, claims: claims(map[string]interface{}{"aud": "account"}), want: IdentificationWrongAudience},
+		{name: "configured audience", config: oidcConfig{ClientID: "api", Audiences: []string{"gateway"}}, claims: claims(map[string]interface{}{"aud": []string{"account", "gateway"}}), want: IdentificationOK},
+		{name: "skip client id check", config: oidcConfig{SkipClientIDCheck: true}, claims: claims(map[string]interface{}{"aud": "account"}), want: IdentificationOK},
+		{name: "skip client id check with audiences", config: oidcConfig{SkipClientIDCheck: true, Audiences: []string{"gateway"}}, claims: claims(nil), want: IdentificationWrongAudience},
+		{name: "authorized party", config: oidcConfig{ClientID: "api", AuthorizedParties: []string{"frontend", "backoffice"}}, claims: claims(nil), want: IdentificationOK},
+		{name: "wrong authorized party", config: oidcConfig{ClientID: "api", AuthorizedParties: []string{"backoffice"}}, claims: claims(nil), want: IdentificationWrongAudience},
+		{name: "missing authorized party", config: oidcConfig{ClientID: "api", AuthorizedParties: []string{"frontend"}}, claims: claims(map[string]interface{}{"azp": nil}), want: IdentificationWrongAudience},
+		{name: "typ header", config: oidcConfig{ClientID: "api", TokenType: "at+jwt"}, typ: "at+jwt", claims: claims(nil), want: IdentificationOK},
+		{name: "typ header with application prefix", config: oidcConfig{ClientID: "api", TokenType: "at+jwt"}, typ: "application/AT+JWT", claims: claims(nil), want: IdentificationOK},
+		{name: "typ claim", config: oidcConfig{ClientID: "api", TokenType: "Bearer"}, typ: "JWT", claims: claims(map[string]interface{}{"typ": "Bearer"}), want: IdentificationOK},
+		{name: "id token instead of access token", config: oidcConfig{ClientID: "api", TokenType: "Bearer"}, typ: "JWT", claims: claims(map[string]interface{}{"typ": "ID"}), want: IdentificationInsufficient},
+		{name: "missing typ", config: oidcConfig{ClientID: "api", TokenType: "at+jwt"}, claims: claims(nil), want: IdentificationInsufficient},
+		{name: "scopes", config: oidcConfig{ClientID: "api", Scopes: []string{"orders"}}, claims: claims(nil), want: IdentificationOK},
+		{name: "scopes from scp claim", config: oidcConfig{ClientID: "api", Scopes: []string{"orders"}}, claims: claims(map[string]interface{}{"scope": nil, "scp": []string{"orders"}}), want: IdentificationOK},
+		{name: "missing scope", config: oidcConfig{ClientID: "api", Scopes: []string{"orders", "admin"}}, claims: claims(nil), want: IdentificationInsufficient},
+		{name: "expired", config: oidcConfig{ClientID: "api", ClockSkew: "30s"}, claims: claims(map[string]interface{}{"exp": now.Add(-time.Minute).Unix()}), want: IdentificationExpired},
+		{name: "expired within clock skew", config: oidcConfig{ClientID: "api", ClockSkew: "30s"}, claims: claims(map[string]interface{}{"exp": now.Add(-10 * time.Second).Unix()}), want: IdentificationOK},
+		{name: "not yet valid", config: oidcConfig{ClientID: "api", ClockSkew: "30s"}, claims: claims(map[string]interface{}{"nbf": now.Add(time.Minute).Unix()}), want: IdentificationNotYetValid},
+		{name: "not yet valid within clock skew", config: oidcConfig{ClientID: "api", ClockSkew: "30s"}, claims: claims(map[string]interface{}{"nbf": now.Add(10 * time.Second).Unix()}), want: IdentificationOK},
+		{name: "missing exp", config: oidcConfig{ClientID: "api"}, claims: claims(map[string]interface{}{"exp": nil}), want: IdentificationExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validation, err := newAccessTokenValidation(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rawToken := signAccessToken(t, key, tt.typ, tt.claims)
+			token, err := oidc.NewVerifier(issuer, keySet, validation.verifierConfig()).Verify(context.Background(), rawToken)
+			if err != nil {
+				t.Fatalf("Verify() unexpected error: %v", err)
+			}
+
+			err = validation.check(token, rawToken)
+			got := IdentificationOK
+			if err != nil {
+				got = identificationStatus(nil, err)
+			}
+			if got != tt.want {
+				t.Errorf("check() = %v (%v), want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAccessTokenValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  oidcConfig
+		wantErr bool
+	}{
+		{name: "client id", config: oidcConfig{ClientID: "api"}},
+		{name: "skip client id check", config: oidcConfig{SkipClientIDCheck: true}},
+		{name: "missing client id", config: oidcConfig{}, wantErr: true},
+		{name: "invalid clock skew", config: oidcConfig{ClientID: "api", ClockSkew: "soon"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newAccessTokenValidation(tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("newAccessTokenValidation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
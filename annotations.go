@@ -0,0 +1,166 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"flamingo.me/grpc/options"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// AnnotationPolicyOrder is the order of the interceptors enforcing the flamingo.grpc.auth proto options
+const AnnotationPolicyOrder = -890
+
+// annotationPolicies enforces the (flamingo.grpc.auth) and (flamingo.grpc.service_auth) options of the called method
+type annotationPolicies struct {
+	enforcer *policyEnforcer
+	cache    sync.Map
+}
+
+func (a *annotationPolicies) Inject(enforcer *policyEnforcer) *annotationPolicies {
+	a.enforcer = enforcer
+	return a
+}
+
+// policyFor reads the options from the registered service descriptor, the method option takes precedence over the service option
+func (a *annotationPolicies) policyFor(fullMethod string) *policyConfig {
+	if cached, ok := a.cache.Load(fullMethod); ok {
+		return cached.(*policyConfig)
+	}
+
+	policy := lookupAnnotationPolicy(fullMethod)
+	a.cache.Store(fullMethod, policy)
+
+	return policy
+}
+
+func lookupAnnotationPolicy(fullMethod string) *policyConfig {
+	parts := strings.Split(strings.TrimPrefix(fullMethod, "/"), "/")
+	if len(parts) != 2 {
+		return nil
+	}
+
+	descriptor, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(parts[0]))
+	if err != nil {
+		return nil
+	}
+	service, ok := descriptor.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil
+	}
+
+	if method := service.Methods().ByName(protoreflect.Name(parts[1])); method != nil {
+		if rule, ok := proto.GetExtension(method.Options(), options.E_Auth).(*options.AuthRule); ok && rule != nil {
+			return policyFromRule(fullMethod, rule)
+		}
+	}
+
+	if rule, ok := proto.GetExtension(service.Options(), options.E_ServiceAuth).(*options.AuthRule); ok && rule != nil {
+		return policyFromRule(fullMethod, rule)
+	}
+
+	return nil
+}
+
+// checkAnnotationIdentifiers reports identifiers named in the options of the registered services which are unknown or
+// not used by the server
+func checkAnnotationIdentifiers(services map[string]grpc.ServiceInfo, known map[string]bool, serverIdentifiers []string) []error {
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	check := func(element string, rule *options.AuthRule) {
+		for _, identifier := range rule.GetIdentifiers() {
+			if !known[identifier] {
+				errs = append(errs, fmt.Errorf("%s: unknown identifier %q in auth option", element, identifier))
+			} else if len(serverIdentifiers) > 0 && !contains(serverIdentifiers, identifier) {
+				errs = append(errs, fmt.Errorf("%s: identifier %q in auth option is not in the identifiers of the server", element, identifier))
+			}
+		}
+	}
+
+	for _, name := range names {
+		descriptor, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			continue
+		}
+		service, ok := descriptor.(protoreflect.ServiceDescriptor)
+		if !ok {
+			continue
+		}
+
+		if rule, ok := proto.GetExtension(service.Options(), options.E_ServiceAuth).(*options.AuthRule); ok {
+			check(name, rule)
+		}
+
+		methods := service.Methods()
+		for i := 0; i < methods.Len(); i++ {
+			if rule, ok := proto.GetExtension(methods.Get(i).Options(), options.E_Auth).(*options.AuthRule); ok {
+				check(fmt.Sprintf("/%s/%s", name, methods.Get(i).Name()), rule)
+			}
+		}
+	}
+
+	return errs
+}
+
+func policyFromRule(fullMethod string, rule *options.AuthRule) *policyConfig {
+	policy := &policyConfig{
+		Method:      fullMethod,
+		Anonymous:   rule.GetAnonymous(),
+		Identifiers: rule.GetIdentifiers(),
+		RealmRoles:  rule.GetRealmRoles(),
+		Scopes:      rule.GetScopes(),
+	}
+
+	if len(rule.GetClientRoles()) > 0 {
+		policy.ClientRoles = make(map[string][]string, len(rule.GetClientRoles()))
+		for _, clientRole := range rule.GetClientRoles() {
+			policy.ClientRoles[clientRole.GetClient()] = append(policy.ClientRoles[clientRole.GetClient()], clientRole.GetRoles()...)
+		}
+	}
+
+	return policy
+}
+
+func (a *annotationPolicies) authorize(ctx context.Context, fullMethod string) error {
+	policy := a.policyFor(fullMethod)
+	if policy == nil {
+		return nil
+	}
+
+	return a.enforcer.enforce(ctx, policy, fullMethod, nil)
+}
+
+func annotationUnaryInterceptor(policies *annotationPolicies) UnaryServerInterceptor {
+	return UnaryServerInterceptor{
+		Order: AnnotationPolicyOrder,
+		Interceptor: func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := policies.authorize(ctx, info.FullMethod); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		},
+	}
+}
+
+func annotationStreamInterceptor(policies *annotationPolicies) StreamServerInterceptor {
+	return StreamServerInterceptor{
+		Order: AnnotationPolicyOrder,
+		Interceptor: func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := policies.authorize(ss.Context(), info.FullMethod); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		},
+	}
+}
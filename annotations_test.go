@@ -0,0 +1,168 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"flamingo.me/grpc/options"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// registerAnnotatedService registers flamingo.grpc.test.AnnotatedService with a service and a method auth option,
+// and flamingo.grpc.test.PlainService without options
+func registerAnnotatedService(t *testing.T) {
+	t.Helper()
+
+	if _, err := protoregistry.GlobalFiles.FindFileByPath("flamingo/grpc/test/annotated.proto"); err == nil {
+		return
+	}
+
+	serviceOptions := new(descriptorpb.ServiceOptions)
+	proto.SetExtension(serviceOptions, options.E_ServiceAuth, &options.AuthRule{Identifiers: []string{"management"}})
+	methodOptions := new(descriptorpb.MethodOptions)
+	proto.SetExtension(methodOptions, options.E_Auth, &options.AuthRule{Identifiers: []string{"customers"}})
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("flamingo/grpc/test/annotated.proto"),
+		Package:    proto.String("flamingo.grpc.test"),
+		Dependency: []string{"google/protobuf/empty.proto"},
+		Syntax:     proto.String("proto3"),
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name:    proto.String("AnnotatedService"),
+			Options: serviceOptions,
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{Name: proto.String("Get"), InputType: proto.String(".google.protobuf.Empty"), OutputType: proto.String(".google.protobuf.Empty"), Options: methodOptions},
+				{Name: proto.String("List"), InputType: proto.String(".google.protobuf.Empty"), OutputType: proto.String(".google.protobuf.Empty")},
+			},
+		}, {
+			Name: proto.String("PlainService"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{Name: proto.String("Get"), InputType: proto.String(".google.protobuf.Empty"), OutputType: proto.String(".google.protobuf.Empty")},
+			},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := protoregistry.GlobalFiles.RegisterFile(file); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckAnnotationIdentifiers(t *testing.T) {
+	registerAnnotatedService(t)
+
+	services := map[string]grpc.ServiceInfo{
+		"flamingo.grpc.test.AnnotatedService": {},
+		"flamingo.grpc.test.Unregistered":     {},
+	}
+
+	tests := []struct {
+		name              string
+		known             []string
+		serverIdentifiers []string
+		wantErrs          int
+	}{
+		{name: "all known", known: []string{"management", "customers"}},
+		{name: "all known and used by the server", known: []string{"management", "customers", "other"}, serverIdentifiers: []string{"management", "customers"}},
+		{name: "unknown service identifier", known: []string{"customers"}, wantErrs: 1},
+		{name: "unknown identifiers", wantErrs: 2},
+		{name: "not used by the server", known: []string{"management", "customers"}, serverIdentifiers: []string{"management"}, wantErrs: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			known := make(map[string]bool, len(tt.known))
+			for _, identifier := range tt.known {
+				known[identifier] = true
+			}
+
+			errs := checkAnnotationIdentifiers(services, known, tt.serverIdentifiers)
+			if len(errs) != tt.wantErrs {
+				t.Errorf("checkAnnotationIdentifiers() = %v, want %d errors", errs, tt.wantErrs)
+			}
+		})
+	}
+}
+
+func TestOptionsPath(t *testing.T) {
+	if got, want := options.File_flamingo_grpc_options_auth_proto.Path(), "flamingo/grpc/options/auth.proto"; got != want {
+		t.Errorf("options are registered as %q, want %q", got, want)
+	}
+}
+
+func TestLookupAnnotationPolicy(t *testing.T) {
+	registerAnnotatedService(t)
+
+	tests := []struct {
+		name            string
+		fullMethod      string
+		wantIdentifiers []string
+	}{
+		{name: "method option takes precedence", fullMethod: "/flamingo.grpc.test.AnnotatedService/Get", wantIdentifiers: []string{"customers"}},
+		{name: "service option", fullMethod: "/flamingo.grpc.test.AnnotatedService/List", wantIdentifiers: []string{"management"}},
+		{name: "service option for unknown method", fullMethod: "/flamingo.grpc.test.AnnotatedService/Unknown", wantIdentifiers: []string{"management"}},
+		{name: "service without options", fullMethod: "/flamingo.grpc.test.PlainService/Get"},
+		{name: "unknown service", fullMethod: "/flamingo.grpc.test.Unknown/Get"},
+		{name: "malformed method", fullMethod: "flamingo.grpc.test.AnnotatedService"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := lookupAnnotationPolicy(tt.fullMethod)
+			if tt.wantIdentifiers == nil {
+				if policy != nil {
+					t.Errorf("lookupAnnotationPolicy() = %+v, want nil", policy)
+				}
+				return
+			}
+			if policy == nil {
+				t.Fatalf("lookupAnnotationPolicy() = nil, want identifiers %q", tt.wantIdentifiers)
+			}
+			if !reflect.DeepEqual(policy.Identifiers, tt.wantIdentifiers) {
+				t.Errorf("identifiers = %q, want %q", policy.Identifiers, tt.wantIdentifiers)
+			}
+		})
+	}
+}
+
+func TestAnnotationPolicies_authorize(t *testing.T) {
+	registerAnnotatedService(t)
+
+	management := &staticCallIdentifier{identifier: "management", identity: &mockIdentity{identifier: "management", subject: "admin", claims: []byte(`{}`)}}
+	customers := &staticCallIdentifier{identifier: "customers", identity: &mockIdentity{identifier: "customers", subject: "customer", claims: []byte(`{}`)}}
+	anonymous := &staticCallIdentifier{identifier: "management", err: errors.New("no token")}
+
+	tests := []struct {
+		name        string
+		identifiers []CallIdentifier
+		fullMethod  string
+		want        codes.Code
+	}{
+		{name: "method rule with its identifier", identifiers: []CallIdentifier{customers}, fullMethod: "/flamingo.grpc.test.AnnotatedService/Get", want: codes.OK},
+		{name: "method rule with the service identifier", identifiers: []CallIdentifier{management}, fullMethod: "/flamingo.grpc.test.AnnotatedService/Get", want: codes.Unauthenticated},
+		{name: "service rule with its identifier", identifiers: []CallIdentifier{management}, fullMethod: "/flamingo.grpc.test.AnnotatedService/List", want: codes.OK},
+		{name: "service rule with the method identifier", identifiers: []CallIdentifier{customers}, fullMethod: "/flamingo.grpc.test.AnnotatedService/List", want: codes.Unauthenticated},
+		{name: "service rule without identity", identifiers: []CallIdentifier{anonymous}, fullMethod: "/flamingo.grpc.test.AnnotatedService/List", want: codes.Unauthenticated},
+		{name: "service without options", identifiers: []CallIdentifier{anonymous}, fullMethod: "/flamingo.grpc.test.PlainService/Get", want: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policies := new(annotationPolicies).Inject(newTestEnforcer(t, "", nil, tt.identifiers...))
+
+			if got := status.Code(policies.authorize(context.Background(), tt.fullMethod)); got != tt.want {
+				t.Errorf("authorize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
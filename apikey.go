@@ -0,0 +1,163 @@
+package grpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"flamingo.me/flamingo/v3/core/auth"
+	"flamingo.me/flamingo/v3/framework/config"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/metadata"
+)
+
+// apiKeyCallIdentifier identifies calls by static API keys, only the SHA-256 hashes of the keys are configured
+type apiKeyCallIdentifier struct {
+	identifier string
+	metakey    string
+	keys       []apiKey
+}
+
+var _ CallIdentifier = new(apiKeyCallIdentifier)
+
+type apiKey struct {
+	hash    []byte
+	subject string
+	claims  []byte
+}
+
+type apiKeyEntry struct {
+	Hash    string `json:"hash"`
+	Subject string `json:"subject"`
+	Claims  string `json:"claims"`
+}
+
+type apiKeyConfig struct {
+	Identifier  string        `json:"identifier"`
+	MetadataKey string        `json:"metadatakey"`
+	Keys        []apiKeyEntry `json:"keys"`
+	KeysFile    string        `json:"keysFile"`
+}
+
+func apiKeyFactory(cfg config.Map) (CallIdentifier, error) {
+	var apiKeyConfig apiKeyConfig
+
+	if err := cfg.MapInto(&apiKeyConfig); err != nil {
+		return nil, err
+	}
+
+	entries := apiKeyConfig.Keys
+	if apiKeyConfig.KeysFile != "" {
+		data, err := ioutil.ReadFile(apiKeyConfig.KeysFile)
+		if err != nil {
+			return nil, err
+		}
+		var fileEntries []apiKeyEntry
+		if err := json.Unmarshal(data, &fileEntries); err != nil {
+			return nil, fmt.Errorf("invalid keys in %s: %w", apiKeyConfig.KeysFile, err)
+		}
+		entries = append(entries, fileEntries...)
+	}
+
+	if len(entries) == 0 {
+		return nil, errors.New("one of keys or keysFile is required")
+	}
+
+	keys := make([]apiKey, len(entries))
+	for i, entry := range entries {
+		hash, err := hex.DecodeString(strings.TrimPrefix(entry.Hash, "sha256:"))
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("key %d: hash must be a hex encoded SHA-256 hash", i)
+		}
+		if entry.Subject == "" {
+			return nil, fmt.Errorf("key %d: no subject set", i)
+		}
+		if entry.Claims == "" {
+			entry.Claims = "{}"
+		}
+		if !json.Valid([]byte(entry.Claims)) {
+			return nil, fmt.Errorf("key %d: claims must be a JSON document", i)
+		}
+
+		keys[i] = apiKey{hash: hash, subject: entry.Subject, claims: []byte(entry.Claims)}
+	}
+
+	if apiKeyConfig.MetadataKey == "" {
+		apiKeyConfig.MetadataKey = "x-api-key"
+	}
+
+	return &apiKeyCallIdentifier{
+		identifier: apiKeyConfig.Identifier,
+		metakey:    apiKeyConfig.MetadataKey,
+		keys:       keys,
+	}, nil
+}
+
+func (identifier *apiKeyCallIdentifier) Identifier() string {
+	return identifier.identifier
+}
+
+func (identifier *apiKeyCallIdentifier) Identify(ctx context.Context) (auth.Identity, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, identificationError(IdentificationNotPresent, "no metadata available")
+	}
+
+	values := md.Get(identifier.metakey)
+	if len(values) == 0 {
+		return nil, identificationError(IdentificationNotPresent, "no api key in metadata %q", identifier.metakey)
+	}
+
+	for _, value := range values {
+		if key := identifier.match(value); key != nil {
+			return &apiKeyIdentity{
+				identifier: identifier.identifier,
+				subject:    key.subject,
+				claims:     key.claims,
+			}, nil
+		}
+	}
+
+	return nil, identificationError(IdentificationInvalidCredentials, "unknown api key")
+}
+
+// match compares the hash of the value with every configured hash in constant time
+func (identifier *apiKeyCallIdentifier) match(value string) *apiKey {
+	hash := sha256.Sum256([]byte(value))
+
+	var match *apiKey
+	for i := range identifier.keys {
+		if subtle.ConstantTimeCompare(hash[:], identifier.keys[i].hash) == 1 && match == nil {
+			match = &identifier.keys[i]
+		}
+	}
+	return match
+}
+
+type apiKeyIdentity struct {
+	identifier string
+	subject    string
+	claims     []byte
+}
+
+func (identity *apiKeyIdentity) Broker() string {
+	return identity.identifier
+}
+
+func (identity *apiKeyIdentity) Subject() string {
+	return identity.subject
+}
+
+func (identity *apiKeyIdentity) AccessTokenClaims(into interface{}) error {
+	return json.Unmarshal(identity.claims, into)
+}
+
+func (identity *apiKeyIdentity) TokenSource() oauth2.TokenSource {
+	return nil
+}
@@ -0,0 +1,80 @@
+package grpc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"flamingo.me/flamingo/v3/framework/config"
+)
+
+func TestApiKeyCallIdentifier_match(t *testing.T) {
+	hash := func(key string) string {
+		sum := sha256.Sum256([]byte(key))
+		return hex.EncodeToString(sum[:])
+	}
+
+	identifier, err := apiKeyFactory(config.Map{
+		"identifier": "partners",
+		"keys": config.Slice{
+			config.Map{"hash": hash("first-key"), "subject": "first", "claims": `{"partner_id": 1}`},
+			config.Map{"hash": "sha256:" + hash("second-key"), "subject": "second"},
+			config.Map{"hash": hash("first-key"), "subject": "duplicate"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		value       string
+		wantSubject string
+		wantClaims  string
+	}{
+		{name: "first key", value: "first-key", wantSubject: "first", wantClaims: `{"partner_id": 1}`},
+		{name: "prefixed hash", value: "second-key", wantSubject: "second", wantClaims: `{}`},
+		{name: "unknown key", value: "third-key"},
+		{name: "hash instead of key", value: hash("first-key")},
+		{name: "empty", value: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := identifier.(*apiKeyCallIdentifier).match(tt.value)
+			if tt.wantSubject == "" {
+				if key != nil {
+					t.Errorf("match() = %q, want no match", key.subject)
+				}
+				return
+			}
+			if key == nil {
+				t.Fatalf("match() = nil, want %q", tt.wantSubject)
+			}
+			if key.subject != tt.wantSubject || string(key.claims) != tt.wantClaims {
+				t.Errorf("match() = %q %s, want %q %s", key.subject, key.claims, tt.wantSubject, tt.wantClaims)
+			}
+		})
+	}
+}
+
+func TestApiKeyFactory(t *testing.T) {
+	tests := []struct {
+		name string
+		keys config.Slice
+	}{
+		{name: "no keys"},
+		{name: "invalid hash", keys: config.Slice{config.Map{"hash": "not-hex", "subject": "a"}}},
+		{name: "short hash", keys: config.Slice{config.Map{"hash": "abcdef", "subject": "a"}}},
+		{name: "no subject", keys: config.Slice{config.Map{"hash": hex.EncodeToString(make([]byte, sha256.Size))}}},
+		{name: "invalid claims", keys: config.Slice{config.Map{"hash": hex.EncodeToString(make([]byte, sha256.Size)), "subject": "a", "claims": "{"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := apiKeyFactory(config.Map{"identifier": "partners", "keys": tt.keys}); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
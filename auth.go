@@ -2,21 +2,70 @@ package grpc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 
+	"cuelang.org/go/cue"
 	"flamingo.me/flamingo/v3/core/auth"
 	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
 )
 
 type CallIdentifierFactory func(config config.Map) (CallIdentifier, error)
 
+// CallIdentifierSchema is a CUE fragment, map bound with the same key as a CallIdentifierFactory,
+// grpc.identifier entries for that provider are validated against it and completed with its defaults before the factory is called
+//
+//	injector.BindMap(new(grpc.CallIdentifierSchema), "custom").ToInstance(grpc.CallIdentifierSchema(`{url: string, timeout: int}`))
+type CallIdentifierSchema string
+
+// apply validates the configuration against the schema and returns it with the defaults of the schema filled in
+func (schema CallIdentifierSchema) apply(cfg config.Map) (config.Map, error) {
+	var runtime cue.Runtime
+
+	schemaInstance, err := runtime.Compile("schema", string(schema))
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	configInstance, err := runtime.Compile("config", data)
+	if err != nil {
+		return nil, err
+	}
+
+	value := schemaInstance.Value().Unify(configInstance.Value())
+	if err := value.Validate(cue.Concrete(true)); err != nil {
+		return nil, err
+	}
+
+	var result config.Map
+	if err := value.Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
 type CallIdentifier interface {
 	Identifier() string
 	Identify(ctx context.Context) (auth.Identity, error)
 }
 
+// loggingIdentifier is implemented by identifiers which log in the background, they get the logger after they are built
+type loggingIdentifier interface {
+	setLogger(logger flamingo.Logger)
+}
+
 type IdentityService struct {
 	identityProviders []CallIdentifier
+	// Logger gets the identification results at debug level, nothing is logged without it
+	Logger flamingo.Logger `inject:",optional"`
 }
 
 func (service *IdentityService) Inject(providers []CallIdentifier) *IdentityService {
@@ -24,13 +73,74 @@ func (service *IdentityService) Inject(providers []CallIdentifier) *IdentityServ
 	return service
 }
 
+// providersFor returns the identifiers allowed for the server handling the call
+func (service *IdentityService) providersFor(ctx context.Context) []CallIdentifier {
+	info, ok := ctx.Value(serverInfoKey{}).(*serverInfo)
+	if !ok || len(info.identifiers) == 0 {
+		return service.identityProviders
+	}
+
+	providers := make([]CallIdentifier, 0, len(info.identifiers))
+	for _, provider := range service.identityProviders {
+		for _, identifier := range info.identifiers {
+			if provider.Identifier() == identifier {
+				providers = append(providers, provider)
+				break
+			}
+		}
+	}
+
+	return providers
+}
+
+// identify asks the provider, or the identity cache of the call if there is one
+func (service *IdentityService) identify(ctx context.Context, provider CallIdentifier) (auth.Identity, error) {
+	if cache, ok := ctx.Value(identityCacheKey{}).(*identityCache); ok {
+		return cache.identify(ctx, provider, service.identifyUncached)
+	}
+	return service.identifyUncached(ctx, provider)
+}
+
+// identifyUncached asks the provider and logs the result at debug level
+func (service *IdentityService) identifyUncached(ctx context.Context, provider CallIdentifier) (auth.Identity, error) {
+	identity, err := provider.Identify(ctx)
+
+	if service.Logger != nil {
+		result := newIdentificationResult(provider.Identifier(), identity, err)
+		logger := service.Logger.WithField(flamingo.LogKeyModule, "grpc").WithField("identifier", result.Identifier)
+		if identity != nil {
+			logger.Debug(fmt.Sprintf("identification %s: subject %q", result.Status, identity.Subject()))
+		} else {
+			logger.Debug(fmt.Sprintf("identification %s: %v", result.Status, result.Err))
+		}
+	}
+
+	return identity, err
+}
+
+// IdentificationResults returns the result of every identifier available for the call, including why a call was not identified
+func (service *IdentityService) IdentificationResults(ctx context.Context) []IdentificationResult {
+	if service == nil {
+		return nil
+	}
+
+	providers := service.providersFor(ctx)
+	results := make([]IdentificationResult, len(providers))
+	for i, provider := range providers {
+		identity, err := service.identify(ctx, provider)
+		results[i] = newIdentificationResult(provider.Identifier(), identity, err)
+	}
+
+	return results
+}
+
 func (service *IdentityService) Identify(ctx context.Context) auth.Identity {
 	if service == nil {
 		return nil
 	}
 
-	for _, provider := range service.identityProviders {
-		if identity, _ := provider.Identify(ctx); identity != nil {
+	for _, provider := range service.providersFor(ctx) {
+		if identity, _ := service.identify(ctx, provider); identity != nil {
 			return identity
 		}
 	}
@@ -43,9 +153,9 @@ func (service *IdentityService) IdentifyFor(ctx context.Context, identifier stri
 		return nil, fmt.Errorf("grpc identity service is nil")
 	}
 
-	for _, provider := range service.identityProviders {
+	for _, provider := range service.providersFor(ctx) {
 		if provider.Identifier() == identifier {
-			return provider.Identify(ctx)
+			return service.identify(ctx, provider)
 		}
 	}
 
@@ -59,8 +169,8 @@ func (service *IdentityService) IdentifyAll(ctx context.Context) []auth.Identity
 
 	var identities []auth.Identity
 
-	for _, provider := range service.identityProviders {
-		if identity, _ := provider.Identify(ctx); identity != nil {
+	for _, provider := range service.providersFor(ctx) {
+		if identity, _ := service.identify(ctx, provider); identity != nil {
 			identities = append(identities, identity)
 		}
 	}
@@ -76,13 +186,14 @@ func (service *IdentityService) IdentifyAs(ctx context.Context, checkType auth.I
 		return nil, fmt.Errorf("grpc identity service is nil")
 	}
 
-	for _, provider := range service.identityProviders {
-		if identity, _ := provider.Identify(ctx); identity != nil {
-			if checkType(identity) {
-				return identity, nil
-			}
+	var results []string
+	for _, provider := range service.providersFor(ctx) {
+		identity, err := service.identify(ctx, provider)
+		if identity != nil && checkType(identity) {
+			return identity, nil
 		}
+		results = append(results, fmt.Sprintf("%s: %s", provider.Identifier(), identificationStatus(identity, err)))
 	}
 
-	return nil, fmt.Errorf("no identity for type %T found", checkType)
+	return nil, fmt.Errorf("no identity for type %T found (%s)", checkType, strings.Join(results, ", "))
 }
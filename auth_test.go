@@ -0,0 +1,69 @@
+package grpc
+
+import (
+	"testing"
+
+	"flamingo.me/flamingo/v3/framework/config"
+)
+
+func TestCallIdentifierSchema_apply(t *testing.T) {
+	schema := CallIdentifierSchema(`{
+	url: string
+	timeout: int | *5
+}`)
+
+	tests := []struct {
+		name        string
+		config      config.Map
+		wantTimeout float64
+		wantErr     bool
+	}{
+		{
+			name:        "default is filled in",
+			config:      config.Map{"identifier": "custom", "provider": "custom", "url": "http://localhost"},
+			wantTimeout: 5,
+		},
+		{
+			name:        "configured value is kept",
+			config:      config.Map{"identifier": "custom", "provider": "custom", "url": "http://localhost", "timeout": 10},
+			wantTimeout: 10,
+		},
+		{
+			name:    "missing required field",
+			config:  config.Map{"identifier": "custom", "provider": "custom"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid type",
+			config:  config.Map{"identifier": "custom", "provider": "custom", "url": "http://localhost", "timeout": "10"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := schema.apply(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var result struct {
+				Identifier string
+				URL        string
+				Timeout    float64
+			}
+			if err := got.MapInto(&result); err != nil {
+				t.Fatal(err)
+			}
+			if result.Timeout != tt.wantTimeout {
+				t.Errorf("timeout = %v, want %v", result.Timeout, tt.wantTimeout)
+			}
+			if result.Identifier != "custom" || result.URL != "http://localhost" {
+				t.Errorf("configured fields not kept: %+v", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package grpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"flamingo.me/flamingo/v3/core/auth"
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/metadata"
+)
+
+// basicCallIdentifier identifies calls by HTTP basic credentials, verified against a htpasswd file
+type basicCallIdentifier struct {
+	identifier string
+	metakey    string
+	htpasswd   *htpasswdFile
+}
+
+var (
+	_ CallIdentifier    = new(basicCallIdentifier)
+	_ loggingIdentifier = new(basicCallIdentifier)
+)
+
+// dummyHash is compared for unknown users, so they take as long as known ones
+var dummyHash struct {
+	once sync.Once
+	hash []byte
+}
+
+type basicConfig struct {
+	Identifier     string `json:"identifier"`
+	MetadataKey    string `json:"metadatakey"`
+	HtpasswdFile   string `json:"htpasswdFile"`
+	ReloadInterval string `json:"reloadInterval"`
+}
+
+func basicFactory(cfg config.Map) (CallIdentifier, error) {
+	var basicConfig basicConfig
+
+	if err := cfg.MapInto(&basicConfig); err != nil {
+		return nil, err
+	}
+
+	if basicConfig.HtpasswdFile == "" {
+		return nil, errors.New("htpasswdFile is required")
+	}
+
+	interval, err := duration("reloadInterval", basicConfig.ReloadInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	htpasswd := &htpasswdFile{file: basicConfig.HtpasswdFile, interval: interval, logger: flamingo.NullLogger{}}
+	if err := htpasswd.load(); err != nil {
+		return nil, err
+	}
+
+	if basicConfig.MetadataKey == "" {
+		basicConfig.MetadataKey = "authorization"
+	}
+
+	return &basicCallIdentifier{
+		identifier: basicConfig.Identifier,
+		metakey:    basicConfig.MetadataKey,
+		htpasswd:   htpasswd,
+	}, nil
+}
+
+func (identifier *basicCallIdentifier) Identifier() string {
+	return identifier.identifier
+}
+
+func (identifier *basicCallIdentifier) setLogger(logger flamingo.Logger) {
+	identifier.htpasswd.logger = logger
+}
+
+func (identifier *basicCallIdentifier) Identify(ctx context.Context) (auth.Identity, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, identificationError(IdentificationNotPresent, "no metadata available")
+	}
+
+	err := identificationError(IdentificationNotPresent, "no basic credentials in metadata %q", identifier.metakey)
+	for _, value := range md.Get(identifier.metakey) {
+		if !strings.HasPrefix(strings.ToLower(value), "basic ") {
+			continue
+		}
+
+		decoded, decodeErr := base64.StdEncoding.DecodeString(strings.TrimSpace(value[len("basic "):]))
+		if decodeErr != nil {
+			err = identificationError(IdentificationMalformed, "malformed basic credentials")
+			continue
+		}
+
+		credentials := strings.SplitN(string(decoded), ":", 2)
+		if len(credentials) != 2 {
+			err = identificationError(IdentificationMalformed, "malformed basic credentials")
+			continue
+		}
+		username, password := credentials[0], credentials[1]
+
+		if err = identifier.htpasswd.verify(username, password); err == nil {
+			return &basicIdentity{identifier: identifier.identifier, subject: username}, nil
+		}
+	}
+
+	return nil, err
+}
+
+// htpasswdFile holds the entries of a htpasswd file, reloaded if the file changes.
+// Supported are bcrypt ($2a$, $2b$, $2y$) and argon2 hashes in the PHC string format ($argon2id$, $argon2i$).
+type htpasswdFile struct {
+	file     string
+	interval time.Duration
+	logger   flamingo.Logger
+
+	mu      sync.RWMutex
+	users   map[string]string
+	modTime time.Time
+	checked time.Time
+}
+
+func (h *htpasswdFile) load() error {
+	info, err := os.Stat(h.file)
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(h.file)
+	if err != nil {
+		return err
+	}
+
+	users := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(entry, ":", 2)
+		if len(fields) != 2 || fields[0] == "" {
+			return fmt.Errorf("%s:%d: malformed entry", h.file, line)
+		}
+		username, hash := fields[0], fields[1]
+		if !strings.HasPrefix(hash, "$2") && !strings.HasPrefix(hash, "$argon2") {
+			return fmt.Errorf("%s:%d: unsupported hash for %q, use bcrypt or argon2", h.file, line, username)
+		}
+		users[username] = hash
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	h.users = users
+	h.modTime = info.ModTime()
+	h.checked = time.Now()
+	h.mu.Unlock()
+
+	return nil
+}
+
+// reload loads the file again if it changed since the last load, at most once per interval.
+// The current entries are kept if the file can not be loaded.
+func (h *htpasswdFile) reload() {
+	h.mu.Lock()
+	if time.Since(h.checked) < h.interval {
+		h.mu.Unlock()
+		return
+	}
+	h.checked = time.Now()
+	modTime := h.modTime
+	h.mu.Unlock()
+
+	info, err := os.Stat(h.file)
+	if err == nil && info.ModTime().Equal(modTime) {
+		return
+	}
+	if err == nil {
+		err = h.load()
+	}
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("keeping current htpasswd entries, reload failed: %v", err))
+		return
+	}
+	h.logger.Info(fmt.Sprintf("reloaded htpasswd file %s", h.file))
+}
+
+func (h *htpasswdFile) verify(username, password string) error {
+	h.reload()
+
+	h.mu.RLock()
+	hash, ok := h.users[username]
+	h.mu.RUnlock()
+
+	if !ok {
+		dummyHash.once.Do(func() {
+			dummyHash.hash, _ = bcrypt.GenerateFromPassword([]byte("unknown user"), bcrypt.DefaultCost)
+		})
+		_ = bcrypt.CompareHashAndPassword(dummyHash.hash, []byte(password))
+		return identificationError(IdentificationInvalidCredentials, "unknown user")
+	}
+
+	if strings.HasPrefix(hash, "$argon2") {
+		return verifyArgon2(hash, password)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return identificationError(IdentificationInvalidCredentials, "invalid password")
+	}
+	return nil
+}
+
+// verifyArgon2 verifies a password against a hash like $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>
+func verifyArgon2(encoded, password string) error {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return errors.New("malformed argon2 hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return errors.New("unsupported argon2 version")
+	}
+
+	var memory, iterations uint32
+	var parallelism uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism); err != nil {
+		return errors.New("malformed argon2 parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return errors.New("malformed argon2 salt")
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return errors.New("malformed argon2 hash")
+	}
+
+	var actual []byte
+	switch parts[1] {
+	case "argon2id":
+		actual = argon2.IDKey([]byte(password), salt, iterations, memory, parallelism, uint32(len(expected)))
+	case "argon2i":
+		actual = argon2.Key([]byte(password), salt, iterations, memory, parallelism, uint32(len(expected)))
+	default:
+		return fmt.Errorf("unsupported argon2 variant %q", parts[1])
+	}
+
+	if subtle.ConstantTimeCompare(actual, expected) != 1 {
+		return identificationError(IdentificationInvalidCredentials, "invalid password")
+	}
+	return nil
+}
+
+type basicIdentity struct {
+	identifier string
+	subject    string
+}
+
+func (identity *basicIdentity) Broker() string {
+	return identity.identifier
+}
+
+func (identity *basicIdentity) Subject() string {
+	return identity.subject
+}
@@ -0,0 +1,145 @@
+package grpc
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func argon2Hash(variant, password string) string {
+	salt := []byte("0123456789abcdef")
+	var hash []byte
+	if variant == "argon2i" {
+		hash = argon2.Key([]byte(password), salt, 1, 1024, 1, 32)
+	} else {
+		hash = argon2.IDKey([]byte(password), salt, 1, 1024, 1, 32)
+	}
+	return fmt.Sprintf("$%s$v=%d$m=1024,t=1,p=1$%s$%s", variant, argon2.Version,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(hash))
+}
+
+func writeHtpasswd(t *testing.T, file string, modTime time.Time, lines ...string) {
+	t.Helper()
+
+	var data []byte
+	for _, line := range lines {
+		data = append(data, line+"\n"...)
+	}
+	if err := ioutil.WriteFile(file, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(file, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHtpasswdFile_verify(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("bcrypt-password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "htpasswd")
+	writeHtpasswd(t, file, time.Now(),
+		"# tools",
+		"bcrypt:"+string(bcryptHash),
+		"argon2id:"+argon2Hash("argon2id", "argon2id-password"),
+		"",
+		"argon2i:"+argon2Hash("argon2i", "argon2i-password"),
+		"broken:$argon2id$v=19$m=1024",
+		"old:$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$aGFzaA",
+	)
+
+	htpasswd := &htpasswdFile{file: file, interval: time.Hour, logger: flamingo.NullLogger{}}
+	if err := htpasswd.load(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		wantErr  bool
+	}{
+		{name: "bcrypt", username: "bcrypt", password: "bcrypt-password"},
+		{name: "bcrypt wrong password", username: "bcrypt", password: "argon2id-password", wantErr: true},
+		{name: "argon2id", username: "argon2id", password: "argon2id-password"},
+		{name: "argon2id wrong password", username: "argon2id", password: "bcrypt-password", wantErr: true},
+		{name: "argon2i", username: "argon2i", password: "argon2i-password"},
+		{name: "argon2i wrong password", username: "argon2i", password: "argon2id-password", wantErr: true},
+		{name: "malformed argon2 hash", username: "broken", password: "password", wantErr: true},
+		{name: "unsupported argon2 version", username: "old", password: "password", wantErr: true},
+		{name: "unknown user", username: "unknown", password: "bcrypt-password", wantErr: true},
+		{name: "empty password", username: "bcrypt", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := htpasswd.verify(tt.username, tt.password); (err != nil) != tt.wantErr {
+				t.Errorf("verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHtpasswdFile_reload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "htpasswd")
+	modTime := time.Now().Add(-time.Hour)
+	writeHtpasswd(t, file, modTime, "first:"+argon2Hash("argon2id", "password"))
+
+	htpasswd := &htpasswdFile{file: file, logger: flamingo.NullLogger{}}
+	if err := htpasswd.load(); err != nil {
+		t.Fatal(err)
+	}
+
+	writeHtpasswd(t, file, modTime.Add(time.Minute), "second:"+argon2Hash("argon2id", "password"))
+	if err := htpasswd.verify("second", "password"); err != nil {
+		t.Errorf("changed file was not reloaded: %v", err)
+	}
+	if err := htpasswd.verify("first", "password"); err == nil {
+		t.Error("removed user is still accepted")
+	}
+
+	writeHtpasswd(t, file, modTime.Add(2*time.Minute), "third:plaintext")
+	if err := htpasswd.verify("second", "password"); err != nil {
+		t.Errorf("entries were not kept after a failed reload: %v", err)
+	}
+
+	if err := os.Remove(file); err != nil {
+		t.Fatal(err)
+	}
+	if err := htpasswd.verify("second", "password"); err != nil {
+		t.Errorf("entries were not kept after the file was removed: %v", err)
+	}
+}
+
+func TestHtpasswdFile_load(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+	}{
+		{name: "malformed entry", lines: []string{"no-separator"}},
+		{name: "empty username", lines: []string{":" + argon2Hash("argon2id", "password")}},
+		{name: "unsupported hash", lines: []string{"user:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g="}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "htpasswd")
+			writeHtpasswd(t, file, time.Now(), tt.lines...)
+
+			htpasswd := &htpasswdFile{file: file, logger: flamingo.NullLogger{}}
+			if err := htpasswd.load(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package grpc
+
+import (
+	"context"
+	"sync"
+
+	"flamingo.me/flamingo/v3/core/auth"
+	"google.golang.org/grpc"
+)
+
+// IdentityCacheOrder is the order of the interceptors attaching the identity cache, interceptors with a higher order can use it
+const IdentityCacheOrder = -1000
+
+type (
+	identityCacheKey struct{}
+
+	// identityCache memoizes the result of every CallIdentifier for a single call
+	identityCache struct {
+		mu      sync.Mutex
+		results map[string]*identityResult
+	}
+
+	identityResult struct {
+		once     sync.Once
+		identity auth.Identity
+		err      error
+	}
+)
+
+// WithIdentityCache returns a context in which every CallIdentifier is asked at most once by the IdentityService
+func WithIdentityCache(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(identityCacheKey{}).(*identityCache); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, identityCacheKey{}, &identityCache{results: make(map[string]*identityResult)})
+}
+
+func (c *identityCache) identify(ctx context.Context, provider CallIdentifier, identify func(context.Context, CallIdentifier) (auth.Identity, error)) (auth.Identity, error) {
+	c.mu.Lock()
+	result, ok := c.results[provider.Identifier()]
+	if !ok {
+		result = new(identityResult)
+		c.results[provider.Identifier()] = result
+	}
+	c.mu.Unlock()
+
+	result.once.Do(func() {
+		result.identity, result.err = identify(ctx, provider)
+	})
+
+	return result.identity, result.err
+}
+
+func identityCacheUnaryInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(WithIdentityCache(ctx), req)
+}
+
+func identityCacheStreamInterceptor(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &contextServerStream{
+		ServerStream: ss,
+		ctx:          WithIdentityCache(ss.Context()),
+	})
+}
@@ -0,0 +1,175 @@
+package grpc
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"path"
+
+	"flamingo.me/flamingo/v3/core/auth"
+	"flamingo.me/flamingo/v3/framework/config"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// CertificateIdentity is the identity of a call authenticated by a verified client certificate
+type CertificateIdentity interface {
+	auth.Identity
+	Certificate() *x509.Certificate
+	// SPIFFEID returns the SPIFFE ID of the certificate, nil if it has none
+	SPIFFEID() *url.URL
+}
+
+// clientCertCallIdentifier identifies calls by the verified peer certificate of the TLS connection
+type clientCertCallIdentifier struct {
+	identifier   string
+	subjectFrom  string
+	trustDomains []string
+	subjects     []string
+}
+
+var _ CallIdentifier = new(clientCertCallIdentifier)
+
+type clientCertConfig struct {
+	Identifier   string   `json:"identifier"`
+	SubjectFrom  string   `json:"subjectFrom"`
+	TrustDomains []string `json:"trustDomains"`
+	Subjects     []string `json:"subjects"`
+}
+
+func clientCertFactory(cfg config.Map) (CallIdentifier, error) {
+	var clientCertConfig clientCertConfig
+
+	if err := cfg.MapInto(&clientCertConfig); err != nil {
+		return nil, err
+	}
+
+	switch clientCertConfig.SubjectFrom {
+	case "":
+		clientCertConfig.SubjectFrom = "cn"
+	case "cn", "dns", "spiffe":
+	default:
+		return nil, fmt.Errorf("unknown subjectFrom %q", clientCertConfig.SubjectFrom)
+	}
+
+	for _, pattern := range clientCertConfig.Subjects {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid subject pattern %q: %w", pattern, err)
+		}
+	}
+
+	return &clientCertCallIdentifier{
+		identifier:   clientCertConfig.Identifier,
+		subjectFrom:  clientCertConfig.SubjectFrom,
+		trustDomains: clientCertConfig.TrustDomains,
+		subjects:     clientCertConfig.Subjects,
+	}, nil
+}
+
+func (identifier *clientCertCallIdentifier) Identifier() string {
+	return identifier.identifier
+}
+
+func (identifier *clientCertCallIdentifier) Identify(ctx context.Context) (auth.Identity, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, identificationError(IdentificationNotPresent, "no peer available")
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil, identificationError(IdentificationNotPresent, "connection is not secured by tls")
+	}
+
+	if len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return nil, identificationError(IdentificationNotPresent, "no verified client certificate")
+	}
+	cert := tlsInfo.State.VerifiedChains[0][0]
+
+	spiffeID, err := spiffeIDOf(cert)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(identifier.trustDomains) > 0 && (spiffeID == nil || !contains(identifier.trustDomains, spiffeID.Host)) {
+		return nil, identificationError(IdentificationInsufficient, "client certificate has no SPIFFE ID of a trusted domain")
+	}
+
+	var subject string
+	switch identifier.subjectFrom {
+	case "cn":
+		subject = cert.Subject.CommonName
+	case "dns":
+		if len(cert.DNSNames) > 0 {
+			subject = cert.DNSNames[0]
+		}
+	case "spiffe":
+		if spiffeID != nil {
+			subject = spiffeID.String()
+		}
+	}
+	if subject == "" {
+		return nil, identificationError(IdentificationInsufficient, "client certificate has no %s subject", identifier.subjectFrom)
+	}
+
+	if len(identifier.subjects) > 0 && !matchesAny(identifier.subjects, subject) {
+		return nil, identificationError(IdentificationInsufficient, "subject %q is not allowed", subject)
+	}
+
+	return &clientCertIdentity{
+		identifier: identifier.identifier,
+		subject:    subject,
+		cert:       cert,
+		spiffeID:   spiffeID,
+	}, nil
+}
+
+// spiffeIDOf returns the SPIFFE ID URI SAN of the certificate, a certificate must not have more than one
+func spiffeIDOf(cert *x509.Certificate) (*url.URL, error) {
+	var spiffeID *url.URL
+	for _, uri := range cert.URIs {
+		if uri.Scheme != "spiffe" {
+			continue
+		}
+		if spiffeID != nil {
+			return nil, identificationError(IdentificationMalformed, "client certificate has more than one SPIFFE ID")
+		}
+		spiffeID = uri
+	}
+	return spiffeID, nil
+}
+
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+type clientCertIdentity struct {
+	identifier string
+	subject    string
+	cert       *x509.Certificate
+	spiffeID   *url.URL
+}
+
+var _ CertificateIdentity = new(clientCertIdentity)
+
+func (identity *clientCertIdentity) Broker() string {
+	return identity.identifier
+}
+
+func (identity *clientCertIdentity) Subject() string {
+	return identity.subject
+}
+
+func (identity *clientCertIdentity) Certificate() *x509.Certificate {
+	return identity.cert
+}
+
+func (identity *clientCertIdentity) SPIFFEID() *url.URL {
+	return identity.spiffeID
+}
@@ -0,0 +1,92 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/url"
+	"testing"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+func peerContext(cert *x509.Certificate) context.Context {
+	state := tls.ConnectionState{}
+	if cert != nil {
+		state.VerifiedChains = [][]*x509.Certificate{{cert}}
+	}
+	return peer.NewContext(context.Background(), &peer.Peer{
+		Addr:     &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 50051},
+		AuthInfo: credentials.TLSInfo{State: state},
+	})
+}
+
+func TestClientCertCallIdentifier_Identify(t *testing.T) {
+	spiffeURI := func(raw string) *url.URL {
+		uri, err := url.Parse(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return uri
+	}
+
+	workload := &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "orders"},
+		DNSNames: []string{"orders.internal"},
+		URIs:     []*url.URL{spiffeURI("https://example.com/docs"), spiffeURI("spiffe://prod.example.com/ns/shop/sa/orders")},
+	}
+	plain := &x509.Certificate{Subject: pkix.Name{CommonName: "legacy"}}
+	ambiguous := &x509.Certificate{
+		URIs: []*url.URL{spiffeURI("spiffe://prod.example.com/a"), spiffeURI("spiffe://prod.example.com/b")},
+	}
+
+	tests := []struct {
+		name        string
+		identifier  *clientCertCallIdentifier
+		ctx         context.Context
+		wantSubject string
+		wantSPIFFE  string
+		want        IdentificationStatus
+	}{
+		{name: "no peer", identifier: &clientCertCallIdentifier{subjectFrom: "cn"}, ctx: context.Background(), want: IdentificationNotPresent},
+		{name: "not tls", identifier: &clientCertCallIdentifier{subjectFrom: "cn"}, ctx: peer.NewContext(context.Background(), &peer.Peer{}), want: IdentificationNotPresent},
+		{name: "no verified certificate", identifier: &clientCertCallIdentifier{subjectFrom: "cn"}, ctx: peerContext(nil), want: IdentificationNotPresent},
+		{name: "common name", identifier: &clientCertCallIdentifier{subjectFrom: "cn"}, ctx: peerContext(workload), wantSubject: "orders", wantSPIFFE: "spiffe://prod.example.com/ns/shop/sa/orders", want: IdentificationOK},
+		{name: "dns", identifier: &clientCertCallIdentifier{subjectFrom: "dns"}, ctx: peerContext(workload), wantSubject: "orders.internal", wantSPIFFE: "spiffe://prod.example.com/ns/shop/sa/orders", want: IdentificationOK},
+		{name: "spiffe", identifier: &clientCertCallIdentifier{subjectFrom: "spiffe"}, ctx: peerContext(workload), wantSubject: "spiffe://prod.example.com/ns/shop/sa/orders", wantSPIFFE: "spiffe://prod.example.com/ns/shop/sa/orders", want: IdentificationOK},
+		{name: "spiffe of trusted domain", identifier: &clientCertCallIdentifier{subjectFrom: "spiffe", trustDomains: []string{"prod.example.com"}}, ctx: peerContext(workload), wantSubject: "spiffe://prod.example.com/ns/shop/sa/orders", wantSPIFFE: "spiffe://prod.example.com/ns/shop/sa/orders", want: IdentificationOK},
+		{name: "spiffe of untrusted domain", identifier: &clientCertCallIdentifier{subjectFrom: "spiffe", trustDomains: []string{"staging.example.com"}}, ctx: peerContext(workload), want: IdentificationInsufficient},
+		{name: "trust domain without spiffe id", identifier: &clientCertCallIdentifier{subjectFrom: "cn", trustDomains: []string{"prod.example.com"}}, ctx: peerContext(plain), want: IdentificationInsufficient},
+		{name: "no spiffe id", identifier: &clientCertCallIdentifier{subjectFrom: "spiffe"}, ctx: peerContext(plain), want: IdentificationInsufficient},
+		{name: "no dns", identifier: &clientCertCallIdentifier{subjectFrom: "dns"}, ctx: peerContext(plain), want: IdentificationInsufficient},
+		{name: "more than one spiffe id", identifier: &clientCertCallIdentifier{subjectFrom: "spiffe"}, ctx: peerContext(ambiguous), want: IdentificationMalformed},
+		{name: "allowed subject", identifier: &clientCertCallIdentifier{subjectFrom: "spiffe", subjects: []string{"spiffe://prod.example.com/ns/shop/*/*"}}, ctx: peerContext(workload), wantSubject: "spiffe://prod.example.com/ns/shop/sa/orders", wantSPIFFE: "spiffe://prod.example.com/ns/shop/sa/orders", want: IdentificationOK},
+		{name: "subject not allowed", identifier: &clientCertCallIdentifier{subjectFrom: "spiffe", subjects: []string{"spiffe://prod.example.com/ns/billing/*/*"}}, ctx: peerContext(workload), want: IdentificationInsufficient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := tt.identifier.Identify(tt.ctx)
+			if got := identificationStatus(identity, err); got != tt.want {
+				t.Fatalf("Identify() = %v (%v), want %v", got, err, tt.want)
+			}
+			if identity == nil {
+				return
+			}
+
+			certIdentity, ok := identity.(CertificateIdentity)
+			if !ok {
+				t.Fatalf("identity %T is no CertificateIdentity", identity)
+			}
+			if certIdentity.Subject() != tt.wantSubject {
+				t.Errorf("Subject() = %q, want %q", certIdentity.Subject(), tt.wantSubject)
+			}
+			if certIdentity.SPIFFEID().String() != tt.wantSPIFFE {
+				t.Errorf("SPIFFEID() = %q, want %q", certIdentity.SPIFFEID(), tt.wantSPIFFE)
+			}
+		})
+	}
+}
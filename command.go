@@ -0,0 +1,76 @@
+package grpc
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"github.com/spf13/cobra"
+)
+
+// grpcCommand provides the "grpc serve" command which runs the grpc servers without the flamingo http server
+func grpcCommand(servers *grpcServers) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "grpc",
+		Short: "grpc server commands",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "serve",
+		Short: "Serve the configured grpc servers without the http server",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return servers.serveStandalone(context.Background())
+		},
+	})
+
+	return cmd
+}
+
+// serveStandalone serves until all servers stopped, a termination signal is received or a server fails to start,
+// then shuts down gracefully. The first startup failure is returned, with exitOnStartupFailure disabled once the
+// remaining servers stopped.
+func (s *grpcServers) serveStandalone(ctx context.Context) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	failures := make(chan error, len(s.servers)+1)
+	s.mu.Lock()
+	s.failures = failures
+	s.mu.Unlock()
+
+	done := s.start(ctx, false)
+
+	var err error
+	for serving := true; serving; {
+		select {
+		case sig := <-signals:
+			s.logger.Info("received " + sig.String() + ", shutting down grpc servers")
+			serving = false
+		case failure := <-failures:
+			if err == nil {
+				err = failure
+			}
+			if s.exitOnFailure {
+				s.logger.Info("shutting down grpc servers after startup failure")
+				serving = false
+			}
+		case <-done:
+			serving = false
+		}
+	}
+
+	s.each((*grpcServer).gracefulStop)
+	s.eventRouter.Dispatch(ctx, &flamingo.ServerShutdownEvent{})
+
+	if err == nil {
+		select {
+		case err = <-failures:
+		default:
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,51 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+)
+
+type nullEventRouter struct{}
+
+func (nullEventRouter) Dispatch(context.Context, flamingo.Event) {}
+
+func TestGrpcServers_serveStandalone(t *testing.T) {
+	occupied, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer occupied.Close()
+
+	for _, exitOnFailure := range []bool{true, false} {
+		servers := &grpcServers{
+			eventRouter:   nullEventRouter{},
+			logger:        flamingo.NullLogger{},
+			exitOnFailure: exitOnFailure,
+			servers: []*grpcServer{{
+				config:      serverConfig{Name: "occupied", Addr: occupied.Addr().String()},
+				eventRouter: nullEventRouter{},
+				logger:      flamingo.NullLogger{},
+			}},
+		}
+
+		result := make(chan error, 1)
+		go func() {
+			result <- servers.serveStandalone(context.Background())
+		}()
+
+		select {
+		case err := <-result:
+			var opErr *net.OpError
+			if !errors.As(err, &opErr) {
+				t.Errorf("exitOnFailure %v: serveStandalone() = %v, want the listen error", exitOnFailure, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("exitOnFailure %v: serveStandalone did not return", exitOnFailure)
+		}
+	}
+}
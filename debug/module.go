@@ -2,20 +2,44 @@ package debug
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"flamingo.me/dingo"
 	"flamingo.me/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Module struct{}
 
 func (*Module) Configure(injector *dingo.Injector) {
-	injector.BindMulti(new(grpc.ServerRegister)).ToProvider(registerProvider)
+	injector.BindMulti(new(grpc.NamedServerRegister)).ToProvider(registerProvider)
 }
 
-func registerProvider(impl *impl) grpc.ServerRegister {
-	return func(server grpc.ServerRegistrar) {
-		RegisterFlamingoGrpcDebugServer(server, impl)
+// CueConfig requires the server the debug service is registered on, it must not be the public default server
+func (*Module) CueConfig() string {
+	return `
+grpc: debug: server: string & !="" & !="default"
+`
+}
+
+func registerProvider(impl *impl, cfg *struct {
+	Server string `inject:"config:grpc.debug.server"`
+}) grpc.NamedServerRegister {
+	if cfg.Server == "" || cfg.Server == grpc.DefaultServer {
+		// never expose the debug service on the public port, even if the config validation is bypassed
+		return grpc.NamedServerRegister{
+			Server:   grpc.DefaultServer,
+			Register: func(grpc.ServerRegistrar) {},
+		}
+	}
+
+	return grpc.NamedServerRegister{
+		Server: cfg.Server,
+		Register: func(server grpc.ServerRegistrar) {
+			RegisterFlamingoGrpcDebugServer(server, impl)
+		},
 	}
 }
 
@@ -29,7 +53,17 @@ func (impl *impl) Inject(identifier *grpc.IdentityService) {
 }
 
 func (impl *impl) Identify(ctx context.Context, _ *IdentifyRequest) (*IdentityResponse, error) {
+	// identities are cached per call by the grpc server, repeated identification is cheap
 	identity := impl.identifier.Identify(ctx)
+	if identity == nil {
+		results := impl.identifier.IdentificationResults(ctx)
+		reasons := make([]string, len(results))
+		for i, result := range results {
+			reasons[i] = fmt.Sprintf("%s: %s", result.Identifier, result.Status)
+		}
+		return nil, status.Errorf(codes.Unauthenticated, "call can not be identified (%s)", strings.Join(reasons, ", "))
+	}
+
 	return &IdentityResponse{
 		Subject:    identity.Subject(),
 		Identifier: identity.Broker(),
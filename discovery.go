@@ -0,0 +1,137 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type discoveryConfig struct {
+	Timeout         string `json:"timeout"`
+	MinBackoff      string `json:"minBackoff"`
+	MaxBackoff      string `json:"maxBackoff"`
+	RefreshInterval string `json:"refreshInterval"`
+}
+
+// oidcDiscovery discovers the OpenID provider on first use and retries failed discoveries with an exponential backoff.
+// The last successfully discovered provider is kept, failed refreshes do not affect it.
+type oidcDiscovery struct {
+	issuer          string
+	client          *http.Client
+	minBackoff      time.Duration
+	maxBackoff      time.Duration
+	refreshInterval time.Duration
+
+	mu        sync.Mutex
+	provider  *oidc.Provider
+	running   bool
+	backoff   time.Duration
+	next      time.Time
+	refreshAt time.Time
+	err       error
+}
+
+func newOIDCDiscovery(issuer string, cfg discoveryConfig) (*oidcDiscovery, error) {
+	timeout, err := duration("discovery.timeout", cfg.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	minBackoff, err := duration("discovery.minBackoff", cfg.MinBackoff)
+	if err != nil {
+		return nil, err
+	}
+	maxBackoff, err := duration("discovery.maxBackoff", cfg.MaxBackoff)
+	if err != nil {
+		return nil, err
+	}
+	refreshInterval, err := duration("discovery.refreshInterval", cfg.RefreshInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	if minBackoff <= 0 {
+		minBackoff = time.Second
+	}
+	if maxBackoff < minBackoff {
+		maxBackoff = minBackoff
+	}
+
+	return &oidcDiscovery{
+		issuer:          issuer,
+		client:          &http.Client{Timeout: timeout},
+		minBackoff:      minBackoff,
+		maxBackoff:      maxBackoff,
+		refreshInterval: refreshInterval,
+	}, nil
+}
+
+// get returns the discovered provider, if there is none yet it is discovered unless the backoff of the last failure is pending.
+// Without a provider the error has codes.Unavailable.
+func (d *oidcDiscovery) get() (*oidc.Provider, error) {
+	d.mu.Lock()
+	provider := d.provider
+	now := time.Now()
+
+	if provider != nil {
+		if d.refreshInterval > 0 && !d.running && now.After(d.refreshAt) {
+			d.running = true
+			go d.discover()
+		}
+		d.mu.Unlock()
+		return provider, nil
+	}
+
+	if d.running || now.Before(d.next) {
+		err := d.err
+		d.mu.Unlock()
+		return nil, d.unavailable(err)
+	}
+	d.running = true
+	d.mu.Unlock()
+
+	provider, err := d.discover()
+	if err != nil {
+		return nil, d.unavailable(err)
+	}
+	return provider, nil
+}
+
+func (d *oidcDiscovery) discover() (*oidc.Provider, error) {
+	// the context is kept by the provider to fetch the signing keys, so it must not be canceled
+	provider, err := oidc.NewProvider(oidc.ClientContext(context.Background(), d.client), d.issuer)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.running = false
+	if err != nil {
+		d.err = err
+		if d.backoff == 0 {
+			d.backoff = d.minBackoff
+		} else if d.backoff *= 2; d.backoff > d.maxBackoff {
+			d.backoff = d.maxBackoff
+		}
+		d.next = time.Now().Add(d.backoff)
+		d.refreshAt = d.next
+		return d.provider, err
+	}
+
+	d.provider = provider
+	d.err = nil
+	d.backoff = 0
+	d.refreshAt = time.Now().Add(d.refreshInterval)
+	return provider, nil
+}
+
+func (d *oidcDiscovery) unavailable(err error) error {
+	if err == nil {
+		return status.Errorf(codes.Unavailable, "discovery of %s pending", d.issuer)
+	}
+	return status.Error(codes.Unavailable, fmt.Sprintf("discovery of %s failed: %v", d.issuer, err))
+}
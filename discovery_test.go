@@ -0,0 +1,101 @@
+package grpc
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestOidcDiscovery_get(t *testing.T) {
+	t.Run("retries failed discovery after the backoff", func(t *testing.T) {
+		issuer := newTestIssuer(t)
+		atomic.StoreInt32(&issuer.discoveryFails, 1)
+
+		discovery, err := newOIDCDiscovery(issuer.URL, discoveryConfig{Timeout: "5s", MinBackoff: "50ms", MaxBackoff: "1s"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := discovery.get(); status.Code(err) != codes.Unavailable {
+			t.Fatalf("get() error = %v, want codes.Unavailable", err)
+		}
+		if _, err := discovery.get(); status.Code(err) != codes.Unavailable {
+			t.Fatalf("get() during backoff error = %v, want codes.Unavailable", err)
+		}
+		if got := atomic.LoadInt32(&issuer.discoveries); got != 1 {
+			t.Fatalf("got %d discoveries during the backoff, want 1", got)
+		}
+
+		atomic.StoreInt32(&issuer.discoveryFails, 0)
+		time.Sleep(60 * time.Millisecond)
+
+		provider, err := discovery.get()
+		if err != nil || provider == nil {
+			t.Fatalf("get() after the backoff = %v, %v, want a provider", provider, err)
+		}
+		if got := atomic.LoadInt32(&issuer.discoveries); got != 2 {
+			t.Errorf("got %d discoveries, want 2", got)
+		}
+	})
+
+	t.Run("backoff grows exponentially up to the maximum", func(t *testing.T) {
+		issuer := newTestIssuer(t)
+		atomic.StoreInt32(&issuer.discoveryFails, 1)
+
+		discovery, err := newOIDCDiscovery(issuer.URL, discoveryConfig{Timeout: "5s", MinBackoff: "10ms", MaxBackoff: "25ms"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, want := range []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 25 * time.Millisecond, 25 * time.Millisecond} {
+			if _, err := discovery.discover(); err == nil {
+				t.Fatal("discover() unexpectedly succeeded")
+			}
+			if discovery.backoff != want {
+				t.Errorf("backoff = %v, want %v", discovery.backoff, want)
+			}
+		}
+
+		atomic.StoreInt32(&issuer.discoveryFails, 0)
+		if _, err := discovery.discover(); err != nil {
+			t.Fatal(err)
+		}
+		if discovery.backoff != 0 {
+			t.Errorf("backoff = %v after success, want it reset", discovery.backoff)
+		}
+	})
+
+	t.Run("keeps the last provider if a refresh fails", func(t *testing.T) {
+		issuer := newTestIssuer(t)
+
+		discovery, err := newOIDCDiscovery(issuer.URL, discoveryConfig{Timeout: "5s", RefreshInterval: "10ms"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		provider, err := discovery.get()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		atomic.StoreInt32(&issuer.discoveryFails, 1)
+		deadline := time.Now().Add(5 * time.Second)
+		for atomic.LoadInt32(&issuer.discoveries) < 3 {
+			if time.Now().After(deadline) {
+				t.Fatal("provider was not refreshed")
+			}
+			time.Sleep(15 * time.Millisecond)
+
+			refreshed, err := discovery.get()
+			if err != nil {
+				t.Fatalf("get() during failed refresh error = %v", err)
+			}
+			if refreshed != provider {
+				t.Fatal("get() returned another provider after a failed refresh")
+			}
+		}
+	})
+}
@@ -0,0 +1,16 @@
+package grpc
+
+import "strings"
+
+// ConfigError lists all problems found in the grpc configuration
+type ConfigError struct {
+	Errors []error
+}
+
+func (e *ConfigError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return "invalid grpc configuration: " + strings.Join(msgs, "; ")
+}
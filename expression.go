@@ -0,0 +1,119 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"flamingo.me/flamingo/v3/core/auth"
+	"flamingo.me/flamingo/v3/core/auth/oauth"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// expressionEnv declares the variables available in policy expressions
+var expressionEnv, expressionEnvErr = cel.NewEnv(
+	cel.Declarations(
+		decls.NewVar("identity", decls.NewMapType(decls.String, decls.Dyn)),
+		decls.NewVar("method", decls.String),
+		decls.NewVar("metadata", decls.NewMapType(decls.String, decls.NewListType(decls.String))),
+		decls.NewVar("request", decls.NewMapType(decls.String, decls.Dyn)),
+	),
+)
+
+// compileExpression compiles and type checks a policy expression, it has to evaluate to a bool
+func compileExpression(expression string) (cel.Program, error) {
+	if expressionEnvErr != nil {
+		return nil, expressionEnvErr
+	}
+
+	ast, issues := expressionEnv.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	if !proto.Equal(ast.ResultType(), decls.Bool) {
+		return nil, fmt.Errorf("expression must evaluate to bool")
+	}
+
+	return expressionEnv.Program(ast)
+}
+
+// identityVariable exposes subject, broker and the access token claims of the identity
+func identityVariable(identity auth.Identity) map[string]interface{} {
+	claims := make(map[string]interface{})
+	if oauthIdentity, ok := identity.(oauth.Identity); ok {
+		_ = oauthIdentity.AccessTokenClaims(&claims)
+	}
+
+	return map[string]interface{}{
+		"subject": identity.Subject(),
+		"broker":  identity.Broker(),
+		"claims":  claims,
+	}
+}
+
+// requestVariable maps the request message to its JSON representation with the field names of the proto definition
+func requestVariable(request interface{}) (map[string]interface{}, error) {
+	res := make(map[string]interface{})
+
+	message, ok := request.(proto.Message)
+	if !ok {
+		return res, nil
+	}
+
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, json.Unmarshal(data, &res)
+}
+
+// allows evaluates the expression of the policy, policies without expression allow every call
+func (policy *policyConfig) allows(ctx context.Context, identity auth.Identity, fullMethod string, request interface{}) bool {
+	if policy.program == nil {
+		return true
+	}
+
+	requestVar, err := requestVariable(request)
+	if err != nil {
+		return false
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	metadataVar := map[string][]string(md)
+	if metadataVar == nil {
+		metadataVar = make(map[string][]string)
+	}
+
+	out, _, err := policy.program.Eval(map[string]interface{}{
+		"identity": identityVariable(identity),
+		"method":   fullMethod,
+		"metadata": metadataVar,
+		"request":  requestVar,
+	})
+	if err != nil {
+		return false
+	}
+
+	allowed, ok := out.Value().(bool)
+	return ok && allowed
+}
+
+// expressionServerStream evaluates the policy expression for every message received on a stream
+type expressionServerStream struct {
+	grpc.ServerStream
+	check func(request interface{}) error
+}
+
+func (s *expressionServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.check(m)
+}
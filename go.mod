@@ -3,12 +3,18 @@ module flamingo.me/grpc
 go 1.16
 
 require (
+	cuelang.org/go v0.0.15
 	flamingo.me/dingo v0.2.9
 	flamingo.me/flamingo/v3 v3.2.2
 	github.com/coreos/go-oidc v2.2.1+incompatible
 	github.com/golang/protobuf v1.4.2
+	github.com/google/cel-go v0.6.0
+	github.com/spf13/cobra v0.0.6
 	go.opencensus.io v0.22.4
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
+	golang.org/x/net v0.0.0-20200822124328-c89045814202
 	golang.org/x/oauth2 v0.0.0-20210514164344-f6687ab2804c
 	google.golang.org/grpc v1.38.0
 	google.golang.org/protobuf v1.25.0
+	gopkg.in/square/go-jose.v2 v2.1.9
 )
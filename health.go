@@ -0,0 +1,102 @@
+package grpc
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"flamingo.me/flamingo/v3/core/healthcheck/domain/healthcheck"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type healthConfig struct {
+	Enabled       bool   `json:"enabled"`
+	Interval      string `json:"interval"`
+	ShutdownDelay string `json:"shutdownDelay"`
+}
+
+// healthService serves grpc.health.v1 and derives the serving status from flamingo's healthcheck status providers
+type healthService struct {
+	server   *health.Server
+	status   map[string]healthcheck.Status
+	interval time.Duration
+	services []string
+	logger   flamingo.Logger
+
+	mu   sync.Mutex
+	stop chan struct{}
+}
+
+func newHealthService(status map[string]healthcheck.Status, interval time.Duration, logger flamingo.Logger) *healthService {
+	return &healthService{
+		server:   health.NewServer(),
+		status:   status,
+		interval: interval,
+		logger:   logger,
+		stop:     make(chan struct{}),
+	}
+}
+
+// register adds the health service to the server, per service status is reported for all services already registered
+func (h *healthService) register(server *grpc.Server) {
+	for name := range server.GetServiceInfo() {
+		h.services = append(h.services, name)
+	}
+	sort.Strings(h.services)
+
+	healthpb.RegisterHealthServer(server, h.server)
+	h.check()
+}
+
+func (h *healthService) check() {
+	serving := true
+	for name, status := range h.status {
+		if alive, details := status.Status(); !alive {
+			serving = false
+			h.logger.Warn(fmt.Sprintf("healthcheck %s is not alive: %s", name, details))
+		}
+	}
+
+	status := healthpb.HealthCheckResponse_SERVING
+	if !serving {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+
+	h.server.SetServingStatus("", status)
+	for _, service := range h.services {
+		h.server.SetServingStatus(service, status)
+	}
+}
+
+// run re-evaluates the status providers until shutdown is called
+func (h *healthService) run() {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+			h.check()
+		}
+	}
+}
+
+// shutdown switches every service to NOT_SERVING so load balancers stop sending traffic
+func (h *healthService) shutdown() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	select {
+	case <-h.stop:
+	default:
+		close(h.stop)
+	}
+
+	h.server.Shutdown()
+}
@@ -0,0 +1,88 @@
+package grpc
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"flamingo.me/flamingo/v3/framework/web"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// httpFilter routes grpc requests arriving at the flamingo http server to the grpc server configured with serveHTTP
+type httpFilter struct {
+	servers *grpcServers
+	h2      *http2.Server
+	router  func() *web.Router
+
+	// handler serves the other requests of h2c connections, built from the router on first use
+	once    sync.Once
+	handler http.Handler
+}
+
+// handledResult marks a request as already answered by the grpc server
+type handledResult struct{}
+
+var _ web.Filter = new(httpFilter)
+
+func (f *httpFilter) Inject(servers *grpcServers, router func() *web.Router) *httpFilter {
+	f.servers = servers
+	f.h2 = new(http2.Server)
+	f.router = router
+	return f
+}
+
+func (f *httpFilter) routerHandler() http.Handler {
+	f.once.Do(func() {
+		if f.handler == nil {
+			f.handler = f.router().Handler()
+		}
+	})
+	return f.handler
+}
+
+func (handledResult) Apply(context.Context, http.ResponseWriter) error {
+	return nil
+}
+
+func isGrpcRequest(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+// isH2CRequest detects cleartext HTTP/2, either with prior knowledge or as an upgrade from HTTP/1.1
+func isH2CRequest(r *http.Request) bool {
+	if r.Method == "PRI" && r.URL.Path == "*" && r.Proto == "HTTP/2.0" {
+		return true
+	}
+	return strings.EqualFold(r.Header.Get("Upgrade"), "h2c") && r.Header.Get("HTTP2-Settings") != ""
+}
+
+func (f *httpFilter) Filter(ctx context.Context, req *web.Request, w http.ResponseWriter, chain *web.FilterChain) web.Result {
+	server := f.servers.httpServer()
+	if server == nil {
+		return chain.Next(ctx, req, w)
+	}
+
+	r := req.Request()
+
+	if isGrpcRequest(r) {
+		server.ServeHTTP(w, r)
+		return handledResult{}
+	}
+
+	if isH2CRequest(r) {
+		// the connection is taken over by the http2 server, all other requests on it go through the flamingo router again
+		h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isGrpcRequest(r) {
+				server.ServeHTTP(w, r)
+				return
+			}
+			f.routerHandler().ServeHTTP(w, r)
+		}), f.h2).ServeHTTP(w, r)
+		return handledResult{}
+	}
+
+	return chain.Next(ctx, req, w)
+}
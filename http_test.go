@@ -0,0 +1,122 @@
+package grpc
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"flamingo.me/flamingo/v3/framework/web"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// wrappedResponseWriter wraps the writer like the flamingo router, passing through Flusher and Hijacker
+type wrappedResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w *wrappedResponseWriter) Flush() {
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w *wrappedResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+type textResult string
+
+func (r textResult) Apply(_ context.Context, w http.ResponseWriter) error {
+	_, err := w.Write([]byte(r))
+	return err
+}
+
+func TestHttpFilter_h2c(t *testing.T) {
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+	defer grpcServer.Stop()
+
+	filter := &httpFilter{servers: &grpcServers{http: grpcServer}, h2: new(http2.Server)}
+
+	// router mimics the flamingo router: every request runs through the filter chain, the final filter is the route
+	router := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		chain := web.NewFilterChain(func(_ context.Context, req *web.Request, _ http.ResponseWriter) web.Result {
+			return textResult("router " + req.Request().Proto + " " + req.Request().URL.Path)
+		}, filter)
+
+		wrapped := &wrappedResponseWriter{ResponseWriter: w}
+		if result := chain.Next(ctx, web.CreateRequest(r, nil), wrapped); result != nil {
+			_ = result.Apply(ctx, wrapped)
+		}
+	})
+	filter.handler = router
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	t.Run("grpc via h2c prior knowledge", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		conn, err := grpc.DialContext(ctx, strings.TrimPrefix(server.URL, "http://"), grpc.WithInsecure(), grpc.WithBlock())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		response, err := healthpb.NewHealthClient(conn).Check(ctx, new(healthpb.HealthCheckRequest))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("got status %v, want SERVING", response.GetStatus())
+		}
+	})
+
+	get := func(t *testing.T, client *http.Client, path string) string {
+		t.Helper()
+
+		response, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		body, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+
+	t.Run("other requests via h2c prior knowledge", func(t *testing.T) {
+		client := &http.Client{Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+				return net.Dial(network, addr)
+			},
+		}}
+
+		if got, want := get(t, client, "/products/1"), "router HTTP/2.0 /products/1"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+		if got, want := get(t, client, "/products/2"), "router HTTP/2.0 /products/2"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("other requests via http/1.1", func(t *testing.T) {
+		if got, want := get(t, server.Client(), "/products/1"), "router HTTP/1.1 /products/1"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+}
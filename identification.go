@@ -0,0 +1,151 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"flamingo.me/flamingo/v3/core/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// IdentificationStatus classifies the result of a CallIdentifier
+type IdentificationStatus string
+
+const (
+	IdentificationOK                 IdentificationStatus = "ok"
+	IdentificationNotPresent         IdentificationStatus = "not present"
+	IdentificationMalformed          IdentificationStatus = "malformed"
+	IdentificationInvalidSignature   IdentificationStatus = "invalid signature"
+	IdentificationInvalidCredentials IdentificationStatus = "invalid credentials"
+	IdentificationExpired            IdentificationStatus = "expired"
+	IdentificationNotYetValid        IdentificationStatus = "not yet valid"
+	IdentificationWrongIssuer        IdentificationStatus = "wrong issuer"
+	IdentificationWrongAudience      IdentificationStatus = "wrong audience"
+	IdentificationInsufficient       IdentificationStatus = "insufficient"
+	IdentificationUnavailable        IdentificationStatus = "unavailable"
+	IdentificationFailed             IdentificationStatus = "failed"
+)
+
+// IdentificationResult is the outcome of a single CallIdentifier for a call
+type IdentificationResult struct {
+	Identifier string
+	Status     IdentificationStatus
+	Identity   auth.Identity
+	Err        error
+}
+
+// IdentificationError can be returned by a CallIdentifier to report why a call was not identified
+type IdentificationError struct {
+	Status IdentificationStatus
+	Err    error
+}
+
+func (e *IdentificationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *IdentificationError) Unwrap() error {
+	return e.Err
+}
+
+func identificationError(status IdentificationStatus, format string, args ...interface{}) error {
+	return &IdentificationError{Status: status, Err: fmt.Errorf(format, args...)}
+}
+
+func newIdentificationResult(identifier string, identity auth.Identity, err error) IdentificationResult {
+	return IdentificationResult{
+		Identifier: identifier,
+		Status:     identificationStatus(identity, err),
+		Identity:   identity,
+		Err:        err,
+	}
+}
+
+// identificationStatus classifies the result, errors without IdentificationError are unavailable for codes.Unavailable,
+// otherwise failed
+func identificationStatus(identity auth.Identity, err error) IdentificationStatus {
+	if identity != nil {
+		return IdentificationOK
+	}
+
+	var identificationErr *IdentificationError
+	if errors.As(err, &identificationErr) {
+		return identificationErr.Status
+	}
+
+	if status.Code(err) == codes.Unavailable {
+		return IdentificationUnavailable
+	}
+
+	return IdentificationFailed
+}
+
+// tokenErrors reports why none of the tokens of a call was accepted, it unwraps to the most specific error
+type tokenErrors struct {
+	errs     []error
+	specific error
+}
+
+// newTokenErrors returns the error for the tokens which were rejected, an unavailable error is returned as is so the
+// grpc status code is kept
+func newTokenErrors(errs []error) error {
+	specific := errs[0]
+	for _, err := range errs[1:] {
+		if errorSpecificity(err) > errorSpecificity(specific) {
+			specific = err
+		}
+	}
+
+	if status.Code(specific) == codes.Unavailable {
+		return specific
+	}
+	return &tokenErrors{errs: errs, specific: specific}
+}
+
+func (e *tokenErrors) Error() string {
+	if len(e.errs) == 1 {
+		return "can not identify call: " + e.errs[0].Error()
+	}
+
+	msgs := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		msgs[i] = fmt.Sprintf("token %d: %v", i+1, err)
+	}
+	return "can not identify call: " + strings.Join(msgs, "; ")
+}
+
+func (e *tokenErrors) Unwrap() error {
+	return e.specific
+}
+
+// errorSpecificity ranks unavailable identifiers first, then classified errors, unclassified errors and missing tokens
+func errorSpecificity(err error) int {
+	switch identificationStatus(nil, err) {
+	case IdentificationUnavailable:
+		return 3
+	case IdentificationFailed:
+		return 1
+	case IdentificationNotPresent:
+		return 0
+	}
+	return 2
+}
+
+// oidcVerifyError classifies the errors of the go-oidc verifier, which only reports them as text.
+// Keys which can not be fetched are reported as codes.Unavailable, not as an invalid signature.
+func oidcVerifyError(err error) error {
+	message := err.Error()
+	switch {
+	case strings.Contains(message, "fetching keys"):
+		return status.Errorf(codes.Unavailable, "oidc keys not available: %v", err)
+	case strings.Contains(message, "malformed jwt"):
+		return &IdentificationError{Status: IdentificationMalformed, Err: err}
+	case strings.Contains(message, "failed to verify signature"), strings.Contains(message, "unsupported algorithm"):
+		return &IdentificationError{Status: IdentificationInvalidSignature, Err: err}
+	case strings.Contains(message, "issued by a different provider"):
+		return &IdentificationError{Status: IdentificationWrongIssuer, Err: err}
+	}
+	return err
+}
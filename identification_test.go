@@ -0,0 +1,64 @@
+package grpc
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestOidcVerifyError(t *testing.T) {
+	tests := []struct {
+		message string
+		want    IdentificationStatus
+	}{
+		{message: "oidc: malformed jwt: square/go-jose: compact JWS format must have three parts", want: IdentificationMalformed},
+		{message: "failed to verify signature: failed to verify id token signature", want: IdentificationInvalidSignature},
+		{message: `failed to verify signature: fetching keys oidc: get keys failed: Get "https://idp/certs": connection refused`, want: IdentificationUnavailable},
+		{message: `oidc: id token signed with unsupported algorithm, expected ["RS256"] got "HS256"`, want: IdentificationInvalidSignature},
+		{message: `oidc: id token issued by a different provider, expected "https://a" got "https://b"`, want: IdentificationWrongIssuer},
+		{message: "oidc: token is expired", want: IdentificationFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			if got := identificationStatus(nil, oidcVerifyError(errors.New(tt.message))); got != tt.want {
+				t.Errorf("oidcVerifyError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTokenErrors(t *testing.T) {
+	expired := identificationError(IdentificationExpired, "token is expired")
+	malformed := identificationError(IdentificationMalformed, "malformed jwt")
+	notPresent := identificationError(IdentificationNotPresent, "no token")
+	unavailable := status.Error(codes.Unavailable, "discovery pending")
+	unclassified := errors.New("something went wrong")
+
+	tests := []struct {
+		name     string
+		errs     []error
+		want     IdentificationStatus
+		wantCode codes.Code
+	}{
+		{name: "single error", errs: []error{expired}, want: IdentificationExpired, wantCode: codes.Unknown},
+		{name: "first of equally specific errors", errs: []error{malformed, expired}, want: IdentificationMalformed, wantCode: codes.Unknown},
+		{name: "classified before unclassified", errs: []error{unclassified, expired}, want: IdentificationExpired, wantCode: codes.Unknown},
+		{name: "unclassified before not present", errs: []error{notPresent, unclassified}, want: IdentificationFailed, wantCode: codes.Unknown},
+		{name: "unavailable first", errs: []error{expired, unavailable, malformed}, want: IdentificationUnavailable, wantCode: codes.Unavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newTokenErrors(tt.errs)
+			if got := identificationStatus(nil, err); got != tt.want {
+				t.Errorf("newTokenErrors() = %v (%v), want %v", got, err, tt.want)
+			}
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("status.Code() = %v, want %v", got, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package grpc
+
+import (
+	"sort"
+
+	"google.golang.org/grpc"
+)
+
+type (
+	// UnaryServerInterceptor is multibound to add a unary interceptor to the grpc server.
+	// Interceptors are chained by ascending Order, interceptors with the same order keep their binding order.
+	//
+	//	injector.BindMulti(new(grpc.UnaryServerInterceptor)).ToInstance(grpc.UnaryServerInterceptor{Order: 100, Interceptor: logging})
+	UnaryServerInterceptor struct {
+		Order       int
+		Interceptor grpc.UnaryServerInterceptor
+	}
+
+	// StreamServerInterceptor is multibound to add a stream interceptor to the grpc server.
+	// Interceptors are chained by ascending Order, interceptors with the same order keep their binding order.
+	StreamServerInterceptor struct {
+		Order       int
+		Interceptor grpc.StreamServerInterceptor
+	}
+)
+
+func chainUnaryInterceptors(interceptors []UnaryServerInterceptor) grpc.ServerOption {
+	sorted := make([]UnaryServerInterceptor, len(interceptors))
+	copy(sorted, interceptors)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Order < sorted[j].Order
+	})
+
+	chain := make([]grpc.UnaryServerInterceptor, 0, len(sorted))
+	for _, interceptor := range sorted {
+		if interceptor.Interceptor != nil {
+			chain = append(chain, interceptor.Interceptor)
+		}
+	}
+
+	return grpc.ChainUnaryInterceptor(chain...)
+}
+
+func chainStreamInterceptors(interceptors []StreamServerInterceptor) grpc.ServerOption {
+	sorted := make([]StreamServerInterceptor, len(interceptors))
+	copy(sorted, interceptors)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Order < sorted[j].Order
+	})
+
+	chain := make([]grpc.StreamServerInterceptor, 0, len(sorted))
+	for _, interceptor := range sorted {
+		if interceptor.Interceptor != nil {
+			chain = append(chain, interceptor.Interceptor)
+		}
+	}
+
+	return grpc.ChainStreamInterceptor(chain...)
+}
@@ -0,0 +1,308 @@
+package grpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"flamingo.me/flamingo/v3/core/auth"
+	"flamingo.me/flamingo/v3/core/auth/oauth"
+	"flamingo.me/flamingo/v3/framework/config"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// introspectionCallIdentifier identifies calls by opaque tokens, validated by an OAuth 2.0 introspection endpoint (RFC 7662)
+type introspectionCallIdentifier struct {
+	identifier   string
+	metakey      string
+	endpoint     string
+	clientID     string
+	clientSecret string
+	client       *http.Client
+	cache        *introspectionCache
+	jwts         bool
+}
+
+var _ CallIdentifier = new(introspectionCallIdentifier)
+
+type introspectionConfig struct {
+	Identifier   string `json:"identifier"`
+	Endpoint     string `json:"endpoint"`
+	ClientID     string `json:"clientID"`
+	ClientSecret string `json:"clientSecret"`
+	MetadataKey  string `json:"metadatakey"`
+	Timeout      string `json:"timeout"`
+	CacheSize    int    `json:"cacheSize"`
+	JWTs         bool   `json:"introspectJWTs"`
+}
+
+func introspectionFactory(cfg config.Map) (CallIdentifier, error) {
+	var introspectionConfig introspectionConfig
+
+	if err := cfg.MapInto(&introspectionConfig); err != nil {
+		return nil, err
+	}
+
+	if _, err := url.ParseRequestURI(introspectionConfig.Endpoint); err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+
+	timeout, err := duration("timeout", introspectionConfig.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if introspectionConfig.MetadataKey == "" {
+		introspectionConfig.MetadataKey = "authorization"
+	}
+
+	return &introspectionCallIdentifier{
+		identifier:   introspectionConfig.Identifier,
+		metakey:      introspectionConfig.MetadataKey,
+		endpoint:     introspectionConfig.Endpoint,
+		clientID:     introspectionConfig.ClientID,
+		clientSecret: introspectionConfig.ClientSecret,
+		client:       &http.Client{Timeout: timeout},
+		cache:        newIntrospectionCache(introspectionConfig.CacheSize),
+		jwts:         introspectionConfig.JWTs,
+	}, nil
+}
+
+func (identifier *introspectionCallIdentifier) Identifier() string {
+	return identifier.identifier
+}
+
+func (identifier *introspectionCallIdentifier) Identify(ctx context.Context) (auth.Identity, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, identificationError(IdentificationNotPresent, "no metadata available")
+	}
+
+	var err, unavailable error
+	for _, value := range md.Get(identifier.metakey) {
+		rawToken, ok := identifier.introspectable(value)
+		if !ok {
+			continue
+		}
+
+		var response *introspectionResponse
+		response, err = identifier.introspect(ctx, rawToken)
+		if err == nil {
+			return &introspectionIdentity{
+				identifier: identifier.identifier,
+				response:   response,
+				rawToken:   rawToken,
+			}, nil
+		}
+		if status.Code(err) == codes.Unavailable {
+			unavailable = err
+		}
+	}
+
+	if unavailable != nil {
+		return nil, unavailable
+	}
+	if err == nil {
+		return nil, identificationError(IdentificationNotPresent, "no bearer token in metadata %q", identifier.metakey)
+	}
+	return nil, fmt.Errorf("can not identify call, last error: %w", err)
+}
+
+// introspectable returns the token of a bearer value. Other credentials and, unless enabled, JWTs are never sent to the endpoint.
+func (identifier *introspectionCallIdentifier) introspectable(value string) (string, bool) {
+	if !strings.HasPrefix(strings.ToLower(value), "bearer ") {
+		return "", false
+	}
+
+	rawToken := strings.TrimSpace(value[len("bearer "):])
+	if rawToken == "" || (!identifier.jwts && isJWT(rawToken)) {
+		return "", false
+	}
+	return rawToken, true
+}
+
+// isJWT reports if the token is a compact JWS with a JSON header
+func isJWT(rawToken string) bool {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+		return false
+	}
+
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return false
+	}
+	var fields struct {
+		Alg string `json:"alg"`
+	}
+	return json.Unmarshal(header, &fields) == nil && fields.Alg != ""
+}
+
+// introspect returns the response for active tokens, cached until the token expires
+func (identifier *introspectionCallIdentifier) introspect(ctx context.Context, rawToken string) (*introspectionResponse, error) {
+	key := sha256.Sum256([]byte(rawToken))
+	if response := identifier.cache.get(key); response != nil {
+		return response, nil
+	}
+
+	form := url.Values{"token": {rawToken}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, identifier.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if identifier.clientID != "" {
+		req.SetBasicAuth(url.QueryEscape(identifier.clientID), url.QueryEscape(identifier.clientSecret))
+	}
+
+	resp, err := identifier.client.Do(req)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "introspection failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, status.Errorf(codes.Unavailable, "introspection failed with status %d", resp.StatusCode)
+	}
+
+	response := new(introspectionResponse)
+	if err := json.NewDecoder(resp.Body).Decode(&response.raw); err != nil {
+		return nil, fmt.Errorf("invalid introspection response: %w", err)
+	}
+	if err := json.Unmarshal(response.raw, &response.claims); err != nil {
+		return nil, fmt.Errorf("invalid introspection response: %w", err)
+	}
+
+	if !response.claims.Active {
+		return nil, identificationError(IdentificationInvalidCredentials, "token is not active")
+	}
+	if response.expired(time.Now()) {
+		return nil, identificationError(IdentificationExpired, "token is expired")
+	}
+
+	identifier.cache.put(key, response)
+
+	return response, nil
+}
+
+type introspectionResponse struct {
+	raw    json.RawMessage
+	claims struct {
+		Active   bool   `json:"active"`
+		Subject  string `json:"sub"`
+		Username string `json:"username"`
+		Exp      int64  `json:"exp"`
+	}
+}
+
+func (response *introspectionResponse) expiry() time.Time {
+	if response.claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(response.claims.Exp, 0)
+}
+
+func (response *introspectionResponse) expired(now time.Time) bool {
+	expiry := response.expiry()
+	return !expiry.IsZero() && !now.Before(expiry)
+}
+
+type introspectionIdentity struct {
+	identifier string
+	response   *introspectionResponse
+	rawToken   string
+}
+
+var _ oauth.Identity = new(introspectionIdentity)
+
+func (identity *introspectionIdentity) Broker() string {
+	return identity.identifier
+}
+
+// Subject returns the sub of the token, or the username if the endpoint does not return a sub
+func (identity *introspectionIdentity) Subject() string {
+	if identity.response.claims.Subject != "" {
+		return identity.response.claims.Subject
+	}
+	return identity.response.claims.Username
+}
+
+func (identity *introspectionIdentity) AccessTokenClaims(into interface{}) error {
+	return json.Unmarshal(identity.response.raw, into)
+}
+
+func (identity *introspectionIdentity) TokenSource() oauth2.TokenSource {
+	return oauth2.StaticTokenSource(&oauth2.Token{
+		AccessToken: identity.rawToken,
+		TokenType:   "Bearer",
+		Expiry:      identity.response.expiry(),
+	})
+}
+
+// introspectionCache holds responses of active tokens with an expiry, keyed by the token hash
+type introspectionCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[[sha256.Size]byte]*introspectionResponse
+}
+
+func newIntrospectionCache(size int) *introspectionCache {
+	return &introspectionCache{
+		size:    size,
+		entries: make(map[[sha256.Size]byte]*introspectionResponse),
+	}
+}
+
+func (c *introspectionCache) get(key [sha256.Size]byte) *introspectionResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	response, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	if response.expired(time.Now()) {
+		delete(c.entries, key)
+		return nil
+	}
+	return response
+}
+
+// put caches the response, tokens without expiry are not cached. If the cache is full expired entries are removed first,
+// then arbitrary ones.
+func (c *introspectionCache) put(key [sha256.Size]byte, response *introspectionResponse) {
+	if c.size <= 0 || response.expiry().IsZero() {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= c.size {
+		now := time.Now()
+		for k, entry := range c.entries {
+			if entry.expired(now) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	for k := range c.entries {
+		if len(c.entries) < c.size {
+			break
+		}
+		delete(c.entries, k)
+	}
+
+	c.entries[key] = response
+}
@@ -0,0 +1,329 @@
+package grpc
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"flamingo.me/flamingo/v3/core/auth"
+	"flamingo.me/flamingo/v3/core/auth/oauth"
+	"flamingo.me/flamingo/v3/framework/config"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/metadata"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// jwtCallIdentifier verifies bearer tokens with locally configured keys, without OpenID discovery
+type jwtCallIdentifier struct {
+	identifier         string
+	metakey            string
+	keys               []jose.JSONWebKey
+	issuer             string
+	audiences          []string
+	signingAlgorithms  []string
+	clockSkew          time.Duration
+	allowMissingExpiry bool
+}
+
+var _ CallIdentifier = new(jwtCallIdentifier)
+
+type jwtConfig struct {
+	Identifier         string   `json:"identifier"`
+	MetadataKey        string   `json:"metadatakey"`
+	JWKSFiles          []string `json:"jwksFiles"`
+	PublicKeyFiles     []string `json:"publicKeyFiles"`
+	HMACSecret         string   `json:"hmacSecret"`
+	Issuer             string   `json:"issuer"`
+	Audiences          []string `json:"audiences"`
+	SigningAlgorithms  []string `json:"signingAlgorithms"`
+	ClockSkew          string   `json:"clockSkew"`
+	AllowMissingExpiry bool     `json:"allowMissingExpiry"`
+}
+
+var (
+	asymmetricAlgorithms = []string{
+		string(jose.RS256), string(jose.RS384), string(jose.RS512),
+		string(jose.PS256), string(jose.PS384), string(jose.PS512),
+		string(jose.ES256), string(jose.ES384), string(jose.ES512),
+		string(jose.EdDSA),
+	}
+	hmacAlgorithms = []string{string(jose.HS256), string(jose.HS384), string(jose.HS512)}
+)
+
+func jwtFactory(cfg config.Map) (CallIdentifier, error) {
+	var jwtConfig jwtConfig
+
+	if err := cfg.MapInto(&jwtConfig); err != nil {
+		return nil, err
+	}
+
+	var keys []jose.JSONWebKey
+	for _, file := range jwtConfig.JWKSFiles {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var keySet jose.JSONWebKeySet
+		if err := json.Unmarshal(data, &keySet); err != nil {
+			return nil, fmt.Errorf("invalid JWKS in %s: %w", file, err)
+		}
+		keys = append(keys, keySet.Keys...)
+	}
+
+	for _, file := range jwtConfig.PublicKeyFiles {
+		fileKeys, err := publicKeysFromPEM(file)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, fileKeys...)
+	}
+
+	signingAlgorithms := jwtConfig.SigningAlgorithms
+	if jwtConfig.HMACSecret != "" {
+		keys = append(keys, jose.JSONWebKey{Key: []byte(jwtConfig.HMACSecret)})
+		if len(signingAlgorithms) == 0 {
+			signingAlgorithms = hmacAlgorithms
+		}
+	}
+	if len(signingAlgorithms) == 0 {
+		signingAlgorithms = asymmetricAlgorithms
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("one of jwksFiles, publicKeyFiles or hmacSecret is required")
+	}
+
+	clockSkew, err := duration("clockSkew", jwtConfig.ClockSkew)
+	if err != nil {
+		return nil, err
+	}
+
+	if jwtConfig.MetadataKey == "" {
+		jwtConfig.MetadataKey = "authorization"
+	}
+
+	return &jwtCallIdentifier{
+		identifier:         jwtConfig.Identifier,
+		metakey:            jwtConfig.MetadataKey,
+		keys:               keys,
+		issuer:             jwtConfig.Issuer,
+		audiences:          jwtConfig.Audiences,
+		signingAlgorithms:  signingAlgorithms,
+		clockSkew:          clockSkew,
+		allowMissingExpiry: jwtConfig.AllowMissingExpiry,
+	}, nil
+}
+
+// publicKeysFromPEM reads PKIX and PKCS #1 public keys as well as certificates
+func publicKeysFromPEM(file string) ([]jose.JSONWebKey, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []jose.JSONWebKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		var key interface{}
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			cert, err = x509.ParseCertificate(block.Bytes)
+			if err == nil {
+				key = cert.PublicKey
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in %s: %w", block.Type, file, err)
+		}
+
+		keys = append(keys, jose.JSONWebKey{Key: key})
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no public keys found in %s", file)
+	}
+
+	return keys, nil
+}
+
+func (identifier *jwtCallIdentifier) Identifier() string {
+	return identifier.identifier
+}
+
+func (identifier *jwtCallIdentifier) Identify(ctx context.Context) (auth.Identity, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, identificationError(IdentificationNotPresent, "no metadata available")
+	}
+
+	var err error
+	for _, rawToken := range md.Get(identifier.metakey) {
+		rawToken = bearerToken(rawToken)
+
+		var identity *jwtIdentity
+		identity, err = identifier.verify(rawToken)
+		if err == nil {
+			return identity, nil
+		}
+	}
+
+	if err == nil {
+		return nil, identificationError(IdentificationNotPresent, "no token in metadata %q", identifier.metakey)
+	}
+	return nil, fmt.Errorf("can not identify call, last error: %w", err)
+}
+
+func (identifier *jwtCallIdentifier) verify(rawToken string) (*jwtIdentity, error) {
+	jws, err := jose.ParseSigned(rawToken)
+	if err != nil {
+		return nil, identificationError(IdentificationMalformed, "malformed jwt: %w", err)
+	}
+	if len(jws.Signatures) != 1 {
+		return nil, identificationError(IdentificationMalformed, "jwt must have exactly one signature")
+	}
+
+	header := jws.Signatures[0].Header
+	if !contains(identifier.signingAlgorithms, header.Algorithm) {
+		return nil, identificationError(IdentificationInvalidSignature, "unsupported signing algorithm %q", header.Algorithm)
+	}
+
+	payload, err := identifier.verifySignature(jws, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims struct {
+		Issuer    string          `json:"iss"`
+		Subject   string          `json:"sub"`
+		Audience  json.RawMessage `json:"aud"`
+		Expiry    *json.Number    `json:"exp"`
+		NotBefore *json.Number    `json:"nbf"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, identificationError(IdentificationMalformed, "malformed jwt claims: %w", err)
+	}
+
+	if identifier.issuer != "" && claims.Issuer != identifier.issuer {
+		return nil, identificationError(IdentificationWrongIssuer, "expected issuer %q, got %q", identifier.issuer, claims.Issuer)
+	}
+
+	if len(identifier.audiences) > 0 {
+		audiences, err := audienceClaim(claims.Audience)
+		if err != nil {
+			return nil, err
+		}
+		if !containsAny(audiences, identifier.audiences) {
+			return nil, identificationError(IdentificationWrongAudience, "expected audience in %q, got %q", identifier.audiences, audiences)
+		}
+	}
+
+	now := time.Now()
+	var expiry time.Time
+	if claims.Expiry == nil && !identifier.allowMissingExpiry {
+		return nil, identificationError(IdentificationMalformed, "token has no exp claim")
+	}
+	if claims.Expiry != nil {
+		exp, err := claims.Expiry.Float64()
+		if err != nil {
+			return nil, identificationError(IdentificationMalformed, "invalid exp claim: %w", err)
+		}
+		expiry = time.Unix(int64(exp), 0)
+		if now.After(expiry.Add(identifier.clockSkew)) {
+			return nil, identificationError(IdentificationExpired, "token is expired (expiry: %v)", expiry)
+		}
+	}
+	if claims.NotBefore != nil {
+		nbf, err := claims.NotBefore.Float64()
+		if err != nil {
+			return nil, identificationError(IdentificationMalformed, "invalid nbf claim: %w", err)
+		}
+		if notBefore := time.Unix(int64(nbf), 0); now.Add(identifier.clockSkew).Before(notBefore) {
+			return nil, identificationError(IdentificationNotYetValid, "token is not valid yet (nbf: %v)", notBefore)
+		}
+	}
+
+	return &jwtIdentity{
+		identifier: identifier.identifier,
+		subject:    claims.Subject,
+		claims:     payload,
+		rawToken:   rawToken,
+		expiry:     expiry,
+	}, nil
+}
+
+// verifySignature tries the keys matching the key id of the token, or all keys if the token has none
+func (identifier *jwtCallIdentifier) verifySignature(jws *jose.JSONWebSignature, keyID string) ([]byte, error) {
+	for _, key := range identifier.keys {
+		if keyID != "" && key.KeyID != "" && key.KeyID != keyID {
+			continue
+		}
+		if payload, err := jws.Verify(key); err == nil {
+			return payload, nil
+		}
+	}
+	return nil, identificationError(IdentificationInvalidSignature, "invalid signature")
+}
+
+func audienceClaim(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return []string{single}, nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, identificationError(IdentificationMalformed, "invalid aud claim: %w", err)
+	}
+	return list, nil
+}
+
+type jwtIdentity struct {
+	identifier string
+	subject    string
+	claims     []byte
+	rawToken   string
+	expiry     time.Time
+}
+
+var _ oauth.Identity = new(jwtIdentity)
+
+func (identity *jwtIdentity) Broker() string {
+	return identity.identifier
+}
+
+func (identity *jwtIdentity) Subject() string {
+	return identity.subject
+}
+
+func (identity *jwtIdentity) AccessTokenClaims(into interface{}) error {
+	return json.Unmarshal(identity.claims, into)
+}
+
+func (identity *jwtIdentity) TokenSource() oauth2.TokenSource {
+	return oauth2.StaticTokenSource(&oauth2.Token{
+		AccessToken: identity.rawToken,
+		TokenType:   "Bearer",
+		Expiry:      identity.expiry,
+	})
+}
@@ -0,0 +1,142 @@
+package grpc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"testing"
+	"time"
+
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+func signJWT(t *testing.T, algorithm jose.SignatureAlgorithm, key interface{}, keyID string, claims map[string]interface{}) string {
+	t.Helper()
+
+	options := new(jose.SignerOptions)
+	if keyID != "" {
+		options = options.WithHeader("kid", keyID)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: algorithm, Key: key}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jws.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestJwtCallIdentifier_verify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmacSecret := []byte("integration-test-secret")
+
+	now := time.Now()
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		claims := map[string]interface{}{
+			"iss": "https://idp.example.com",
+			"sub": "user",
+			"aud": "api",
+			"exp": now.Add(time.Hour).Unix(),
+		}
+		for key, value := range overrides {
+			if value == nil {
+				delete(claims, key)
+				continue
+			}
+			claims[key] = value
+		}
+		return claims
+	}
+
+	identifier := func(modify func(identifier *jwtCallIdentifier)) *jwtCallIdentifier {
+		identifier := &jwtCallIdentifier{
+			identifier: "local",
+			keys: []jose.JSONWebKey{
+				{Key: &otherKey.PublicKey, KeyID: "other"},
+				{Key: &rsaKey.PublicKey, KeyID: "signer"},
+				{Key: hmacSecret},
+			},
+			issuer:            "https://idp.example.com",
+			audiences:         []string{"api", "internal"},
+			signingAlgorithms: asymmetricAlgorithms,
+			clockSkew:         30 * time.Second,
+		}
+		if modify != nil {
+			modify(identifier)
+		}
+		return identifier
+	}
+
+	tests := []struct {
+		name       string
+		identifier *jwtCallIdentifier
+		token      string
+		want       IdentificationStatus
+	}{
+		{name: "valid", identifier: identifier(nil), token: signJWT(t, jose.RS256, rsaKey, "signer", claims(nil)), want: IdentificationOK},
+		{name: "valid without kid", identifier: identifier(nil), token: signJWT(t, jose.RS256, rsaKey, "", claims(nil)), want: IdentificationOK},
+		{name: "malformed", identifier: identifier(nil), token: "not-a-jwt", want: IdentificationMalformed},
+		{name: "algorithm not accepted", identifier: identifier(nil), token: signJWT(t, jose.HS256, hmacSecret, "", claims(nil)), want: IdentificationInvalidSignature},
+		{name: "hmac accepted", identifier: identifier(func(identifier *jwtCallIdentifier) {
+			identifier.signingAlgorithms = hmacAlgorithms
+		}), token: signJWT(t, jose.HS256, hmacSecret, "", claims(nil)), want: IdentificationOK},
+		{name: "rsa not accepted for hmac", identifier: identifier(func(identifier *jwtCallIdentifier) {
+			identifier.signingAlgorithms = hmacAlgorithms
+		}), token: signJWT(t, jose.RS256, rsaKey, "signer", claims(nil)), want: IdentificationInvalidSignature},
+		{name: "kid mismatch", identifier: identifier(nil), token: signJWT(t, jose.RS256, rsaKey, "other", claims(nil)), want: IdentificationInvalidSignature},
+		{name: "unknown kid", identifier: identifier(nil), token: signJWT(t, jose.RS256, rsaKey, "unknown", claims(nil)), want: IdentificationInvalidSignature},
+		{name: "unknown key", identifier: identifier(nil), token: signJWT(t, jose.RS256, otherKey, "signer", claims(nil)), want: IdentificationInvalidSignature},
+		{name: "wrong issuer", identifier: identifier(nil), token: signJWT(t, jose.RS256, rsaKey, "signer", claims(map[string]interface{}{"iss": "https://other.example.com"})), want: IdentificationWrongIssuer},
+		{name: "audience list", identifier: identifier(nil), token: signJWT(t, jose.RS256, rsaKey, "signer", claims(map[string]interface{}{"aud": []string{"other", "internal"}})), want: IdentificationOK},
+		{name: "wrong audience", identifier: identifier(nil), token: signJWT(t, jose.RS256, rsaKey, "signer", claims(map[string]interface{}{"aud": "other"})), want: IdentificationWrongAudience},
+		{name: "missing audience", identifier: identifier(nil), token: signJWT(t, jose.RS256, rsaKey, "signer", claims(map[string]interface{}{"aud": nil})), want: IdentificationWrongAudience},
+		{name: "audience not checked", identifier: identifier(func(identifier *jwtCallIdentifier) {
+			identifier.audiences = nil
+		}), token: signJWT(t, jose.RS256, rsaKey, "signer", claims(map[string]interface{}{"aud": "other"})), want: IdentificationOK},
+		{name: "expired", identifier: identifier(nil), token: signJWT(t, jose.RS256, rsaKey, "signer", claims(map[string]interface{}{"exp": now.Add(-time.Minute).Unix()})), want: IdentificationExpired},
+		{name: "expired within clock skew", identifier: identifier(nil), token: signJWT(t, jose.RS256, rsaKey, "signer", claims(map[string]interface{}{"exp": now.Add(-10 * time.Second).Unix()})), want: IdentificationOK},
+		{name: "not yet valid", identifier: identifier(nil), token: signJWT(t, jose.RS256, rsaKey, "signer", claims(map[string]interface{}{"nbf": now.Add(time.Minute).Unix()})), want: IdentificationNotYetValid},
+		{name: "not yet valid within clock skew", identifier: identifier(nil), token: signJWT(t, jose.RS256, rsaKey, "signer", claims(map[string]interface{}{"nbf": now.Add(10 * time.Second).Unix()})), want: IdentificationOK},
+		{name: "missing exp", identifier: identifier(nil), token: signJWT(t, jose.RS256, rsaKey, "signer", claims(map[string]interface{}{"exp": nil})), want: IdentificationMalformed},
+		{name: "missing exp allowed", identifier: identifier(func(identifier *jwtCallIdentifier) {
+			identifier.allowMissingExpiry = true
+		}), token: signJWT(t, jose.RS256, rsaKey, "signer", claims(map[string]interface{}{"exp": nil})), want: IdentificationOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := tt.identifier.verify(tt.token)
+
+			if identity == nil {
+				if got := identificationStatus(nil, err); got != tt.want {
+					t.Fatalf("verify() = %v (%v), want %v", got, err, tt.want)
+				}
+				return
+			}
+			if tt.want != IdentificationOK {
+				t.Fatalf("verify() = ok, want %v", tt.want)
+			}
+			if identity.Subject() != "user" {
+				t.Errorf("Subject() = %q, want %q", identity.Subject(), "user")
+			}
+		})
+	}
+}
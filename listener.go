@@ -0,0 +1,167 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ServerListener is multibound to serve a named server on a pre-created listener instead of its configured address
+//
+//	injector.BindMulti(new(grpc.ServerListener)).ToInstance(grpc.ServerListener{Server: grpc.DefaultServer, Listener: listener})
+type ServerListener struct {
+	Server   string
+	Listener net.Listener
+}
+
+const (
+	unixPrefix    = "unix:"
+	systemdPrefix = "systemd:"
+
+	// listenFdsStart is the first file descriptor passed by systemd, see sd_listen_fds(3)
+	listenFdsStart = 3
+)
+
+var activation struct {
+	once  sync.Once
+	files []*os.File
+	names []string
+	used  map[int]bool
+	err   error
+	mu    sync.Mutex
+}
+
+// listen creates a listener for the given address, supported are
+//
+//	host:port             tcp
+//	unix:/path/to.sock    unix domain socket, created with the given file mode
+//	systemd:              first socket passed via systemd socket activation
+//	systemd:<name|index>  socket passed via systemd socket activation, selected by FileDescriptorName or index
+func listen(addr string, socketMode string) (net.Listener, error) {
+	switch {
+	case strings.HasPrefix(addr, unixPrefix):
+		return listenUnix(strings.TrimPrefix(addr, unixPrefix), socketMode)
+	case strings.HasPrefix(addr, systemdPrefix):
+		return listenSystemd(strings.TrimPrefix(addr, systemdPrefix))
+	default:
+		return net.Listen("tcp", addr)
+	}
+}
+
+func listenUnix(path string, socketMode string) (net.Listener, error) {
+	if info, err := os.Stat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		// a socket left over from a previous run would block the listener
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("unable to remove stale socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if socketMode != "" {
+		mode, err := strconv.ParseUint(socketMode, 8, 32)
+		if err != nil {
+			_ = listener.Close()
+			return nil, fmt.Errorf("invalid socketMode %q: %w", socketMode, err)
+		}
+		if err := os.Chmod(path, os.FileMode(mode)); err != nil {
+			_ = listener.Close()
+			return nil, fmt.Errorf("unable to set socket permissions: %w", err)
+		}
+	}
+
+	return listener, nil
+}
+
+// activatedFiles returns the file descriptors passed by systemd, the environment is consumed on first use
+func activatedFiles() ([]*os.File, []string, error) {
+	activation.once.Do(func() {
+		defer func() {
+			_ = os.Unsetenv("LISTEN_PID")
+			_ = os.Unsetenv("LISTEN_FDS")
+			_ = os.Unsetenv("LISTEN_FDNAMES")
+		}()
+
+		pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+		if err != nil || pid != os.Getpid() {
+			activation.err = errors.New("socket activation: LISTEN_PID not set for this process")
+			return
+		}
+
+		count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+		if err != nil || count < 1 {
+			activation.err = errors.New("socket activation: no file descriptors passed in LISTEN_FDS")
+			return
+		}
+
+		var names []string
+		if fdNames := os.Getenv("LISTEN_FDNAMES"); fdNames != "" {
+			names = strings.Split(fdNames, ":")
+		}
+
+		activation.used = make(map[int]bool)
+		for i := 0; i < count; i++ {
+			name := strconv.Itoa(i)
+			if i < len(names) {
+				name = names[i]
+			}
+			activation.names = append(activation.names, name)
+			activation.files = append(activation.files, os.NewFile(uintptr(listenFdsStart+i), name))
+		}
+	})
+
+	return activation.files, activation.names, activation.err
+}
+
+func listenSystemd(selector string) (net.Listener, error) {
+	files, names, err := activatedFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	index := -1
+	if selector == "" {
+		index = 0
+	} else if i, err := strconv.Atoi(selector); err == nil {
+		index = i
+	} else {
+		for i, name := range names {
+			if name == selector {
+				index = i
+				break
+			}
+		}
+	}
+
+	if index < 0 || index >= len(files) {
+		return nil, fmt.Errorf("socket activation: no socket %q passed", selector)
+	}
+
+	activation.mu.Lock()
+	defer activation.mu.Unlock()
+
+	if activation.used[index] {
+		return nil, fmt.Errorf("socket activation: socket %q is already in use", selector)
+	}
+
+	listener, err := net.FileListener(files[index])
+	if err != nil {
+		return nil, fmt.Errorf("socket activation: %w", err)
+	}
+
+	activation.used[index] = true
+	// net.FileListener works on a duplicate, the inherited descriptor is not needed anymore
+	_ = files[index].Close()
+
+	return listener, nil
+}
@@ -3,35 +3,66 @@ package grpc
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 
 	"flamingo.me/flamingo/v3/core/auth"
 	"flamingo.me/flamingo/v3/framework/config"
 	"golang.org/x/oauth2"
+	"google.golang.org/grpc/metadata"
 )
 
 type mockCallIdentifier struct {
 	identifier string
 	subject    string
 	claims     []byte
+	header     string
+	personas   map[string]mockPersona
 }
 
 var _ CallIdentifier = new(mockCallIdentifier)
 
+type mockPersona struct {
+	Subject string `json:"subject"`
+	Claims  string `json:"claims"`
+}
+
 func mockFactory(cfg config.Map) (CallIdentifier, error) {
 	var config struct {
 		Identifier string
 		Subject    string
 		Claims     string
+		Header     string
+		Personas   map[string]mockPersona
 	}
 
 	if err := cfg.MapInto(&config); err != nil {
 		return nil, err
 	}
 
+	if config.Header != "" && len(config.Personas) == 0 {
+		return nil, fmt.Errorf("header %q requires personas", config.Header)
+	}
+	if config.Header == "" && len(config.Personas) > 0 {
+		return nil, errors.New("personas require a header")
+	}
+
+	for name, persona := range config.Personas {
+		if persona.Claims == "" {
+			persona.Claims = "{}"
+		}
+		if !json.Valid([]byte(persona.Claims)) {
+			return nil, fmt.Errorf("persona %q: claims must be a JSON document", name)
+		}
+		config.Personas[name] = persona
+	}
+
 	return &mockCallIdentifier{
 		identifier: config.Identifier,
 		subject:    config.Subject,
 		claims:     []byte(config.Claims),
+		header:     config.Header,
+		personas:   config.Personas,
 	}, nil
 }
 
@@ -61,10 +92,36 @@ func (identity *mockIdentity) TokenSource() oauth2.TokenSource {
 	return nil
 }
 
+// Identify returns the configured identity, or if a header is configured, the persona selected by the header value.
+// Calls without the header are not identified.
 func (identifier *mockCallIdentifier) Identify(ctx context.Context) (auth.Identity, error) {
+	if identifier.header == "" {
+		return &mockIdentity{
+			identifier: identifier.identifier,
+			subject:    identifier.subject,
+			claims:     identifier.claims,
+		}, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(identifier.header)
+	if len(values) == 0 {
+		return nil, identificationError(IdentificationNotPresent, "no mock persona in metadata %q", identifier.header)
+	}
+
+	persona, ok := identifier.personas[values[0]]
+	if !ok {
+		return nil, identificationError(IdentificationInvalidCredentials, "unknown mock persona %q", values[0])
+	}
+
+	subject := persona.Subject
+	if subject == "" {
+		subject = values[0]
+	}
+
 	return &mockIdentity{
 		identifier: identifier.identifier,
-		subject:    identifier.subject,
-		claims:     identifier.claims,
+		subject:    subject,
+		claims:     []byte(persona.Claims),
 	}, nil
 }
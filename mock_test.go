@@ -0,0 +1,107 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"flamingo.me/flamingo/v3/framework/config"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestMockFactory(t *testing.T) {
+	personas := config.Map{"admin": config.Map{"subject": "admin-user", "claims": `{"admin": true}`}}
+
+	tests := []struct {
+		name    string
+		cfg     config.Map
+		wantErr bool
+	}{
+		{name: "static identity", cfg: config.Map{"identifier": "mock", "subject": "developer", "claims": "{}"}},
+		{name: "personas", cfg: config.Map{"identifier": "mock", "header": "x-mock-persona", "personas": personas}},
+		{name: "persona without claims", cfg: config.Map{"identifier": "mock", "header": "x-mock-persona", "personas": config.Map{"guest": config.Map{}}}},
+		{name: "header without personas", cfg: config.Map{"identifier": "mock", "header": "x-mock-persona"}, wantErr: true},
+		{name: "personas without header", cfg: config.Map{"identifier": "mock", "personas": personas}, wantErr: true},
+		{name: "invalid persona claims", cfg: config.Map{"identifier": "mock", "header": "x-mock-persona", "personas": config.Map{"admin": config.Map{"claims": "{"}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := mockFactory(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("mockFactory() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMockCallIdentifier_Identify(t *testing.T) {
+	identifier, err := mockFactory(config.Map{
+		"identifier": "mock",
+		"header":     "x-mock-persona",
+		"personas": config.Map{
+			"admin": config.Map{"subject": "admin-user", "claims": `{"admin": true}`},
+			"guest": config.Map{},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		persona     string
+		want        IdentificationStatus
+		wantSubject string
+	}{
+		{name: "no header", want: IdentificationNotPresent},
+		{name: "persona", persona: "admin", want: IdentificationOK, wantSubject: "admin-user"},
+		{name: "persona named after its key", persona: "guest", want: IdentificationOK, wantSubject: "guest"},
+		{name: "unknown persona", persona: "root", want: IdentificationInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.persona != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-mock-persona", tt.persona))
+			}
+
+			identity, err := identifier.Identify(ctx)
+			if got := identificationStatus(identity, err); got != tt.want {
+				t.Fatalf("Identify() status = %v, want %v (%v)", got, tt.want, err)
+			}
+			if identity != nil && identity.Subject() != tt.wantSubject {
+				t.Errorf("Identify() subject = %q, want %q", identity.Subject(), tt.wantSubject)
+			}
+		})
+	}
+}
+
+func TestMockIdentifierSchema(t *testing.T) {
+	schema := CallIdentifierSchema(new(Module).CueConfig() + "\nmock: grpc.MockIdentifier\n")
+
+	tests := []struct {
+		name     string
+		personas config.Map
+		wantErr  bool
+	}{
+		{name: "persona", personas: config.Map{"admin": config.Map{"subject": "admin-user", "claims": "{}"}}},
+		{name: "persona with defaults", personas: config.Map{"guest": config.Map{}}},
+		{name: "unknown persona field", personas: config.Map{"admin": config.Map{"subjekt": "admin-user"}}, wantErr: true},
+		{name: "claims not a string", personas: config.Map{"admin": config.Map{"claims": config.Map{"admin": true}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := schema.apply(config.Map{"mock": config.Map{"provider": "mock", "identifier": "mock", "header": "x-mock-persona", "personas": tt.personas}})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if _, err := mockFactory(config.Map(got["mock"].(map[string]interface{}))); err != nil {
+				t.Errorf("mockFactory() error = %v", err)
+			}
+		})
+	}
+}
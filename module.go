@@ -1,16 +1,13 @@
 package grpc
 
 import (
-	"context"
 	"fmt"
-	"log"
-	"net"
 
 	"flamingo.me/dingo"
 	"flamingo.me/flamingo/v3/framework/config"
 	"flamingo.me/flamingo/v3/framework/flamingo"
-	"go.opencensus.io/plugin/ocgrpc"
-	"go.opencensus.io/trace"
+	"flamingo.me/flamingo/v3/framework/web"
+	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
 )
 
@@ -21,60 +18,296 @@ type ServerRegister func(server ServerRegistrar)
 type Module struct{}
 
 func (*Module) Configure(injector *dingo.Injector) {
+	injector.Bind(new(identifierSet)).ToProvider(buildIdentifierSet).In(dingo.Singleton)
 	injector.Bind(new([]CallIdentifier)).ToProvider(buildIdentifier)
 	injector.Bind(new(IdentityService)).In(dingo.ChildSingleton)
 	injector.BindMap(new(CallIdentifierFactory), "oauth2").ToInstance(oauth2Factory)
 	injector.BindMap(new(CallIdentifierFactory), "mock").ToInstance(mockFactory)
+	injector.BindMap(new(CallIdentifierFactory), "introspection").ToInstance(introspectionFactory)
+	injector.BindMap(new(CallIdentifierFactory), "jwt").ToInstance(jwtFactory)
+	injector.BindMap(new(CallIdentifierFactory), "clientcert").ToInstance(clientCertFactory)
+	injector.BindMap(new(CallIdentifierFactory), "apikey").ToInstance(apiKeyFactory)
+	injector.BindMap(new(CallIdentifierFactory), "basic").ToInstance(basicFactory)
 }
 
 func (*Module) CueConfig() string {
 	return `
 grpc: {
-	identifier: _
+	OAuth2Identifier :: {
+		provider: "oauth2"
+		identifier: string
+		issuer: string
+		clientID: string | *""
+		metadatakey: string | *"authorization"
+		audiences: *[] | [...string]
+		skipClientIDCheck: bool | *false
+		authorizedParties: *[] | [...string]
+		scopes: *[] | [...string]
+		signingAlgorithms: *[] | [...string]
+		clockSkew: string | *"0s"
+		tokenType: string | *""
+		discovery: {
+			timeout: string | *"10s"
+			minBackoff: string | *"1s"
+			maxBackoff: string | *"1m"
+			refreshInterval: string | *"1h"
+		}
+	}
+	MockPersona :: {
+		subject: string | *""
+		claims: string | *"{}"
+	}
+	MockIdentifier :: {
+		provider: "mock"
+		identifier: string
+		subject: string | *""
+		claims: string | *"{}"
+		header: string | *""
+		personas: {[string]: MockPersona}
+	}
+	IntrospectionIdentifier :: {
+		provider: "introspection"
+		identifier: string
+		endpoint: string
+		clientID: string | *""
+		clientSecret: string | *""
+		metadatakey: string | *"authorization"
+		timeout: string | *"5s"
+		cacheSize: int | *1000
+		introspectJWTs: bool | *false
+	}
+	JWTIdentifier :: {
+		provider: "jwt"
+		identifier: string
+		metadatakey: string | *"authorization"
+		jwksFiles: *[] | [...string]
+		publicKeyFiles: *[] | [...string]
+		hmacSecret: string | *""
+		issuer: string | *""
+		audiences: *[] | [...string]
+		signingAlgorithms: *[] | [...string]
+		clockSkew: string | *"0s"
+		allowMissingExpiry: bool | *false
+	}
+	ClientCertIdentifier :: {
+		provider: "clientcert"
+		identifier: string
+		subjectFrom: *"cn" | "dns" | "spiffe"
+		trustDomains: *[] | [...string]
+		subjects: *[] | [...string]
+	}
+	APIKey :: {
+		hash: string
+		subject: string
+		claims: string | *"{}"
+	}
+	APIKeyIdentifier :: {
+		provider: "apikey"
+		identifier: string
+		metadatakey: string | *"x-api-key"
+		keys: *[] | [...APIKey]
+		keysFile: string | *""
+	}
+	BasicIdentifier :: {
+		provider: "basic"
+		identifier: string
+		metadatakey: string | *"authorization"
+		htpasswdFile: string
+		reloadInterval: string | *"10s"
+	}
+	// identifiers of providers from other modules, validated against their CallIdentifierSchema
+	CustomIdentifier :: {
+		provider: string & !="oauth2" & !="mock" & !="introspection" & !="jwt" & !="clientcert" & !="apikey" & !="basic"
+		identifier: string
+		...
+	}
+	Identifier :: OAuth2Identifier | MockIdentifier | IntrospectionIdentifier | JWTIdentifier | ClientCertIdentifier | APIKeyIdentifier | BasicIdentifier | CustomIdentifier
+
+	identifier: *[] | [...Identifier]
 	addr: string | *":11101"
+	socketMode: string | *"0660"
+	serveHTTP: bool | *false
+	drainTimeout: string | *"30s"
+	exitOnStartupFailure: bool | *true
+	TLS :: {
+		enabled: bool | *false
+		certFile: string | *""
+		keyFile: string | *""
+		clientCAFile: string | *""
+		clientAuth: *"none" | "request" | "require" | "verify" | "require-and-verify"
+		minVersion: *"1.2" | "1.0" | "1.1" | "1.3"
+		cipherSuites: *[] | [...string]
+		reloadInterval: string | *"1m"
+	}
+	ServerOptions :: {
+		maxRecvMsgSize: int | *0
+		maxSendMsgSize: int | *0
+		maxConcurrentStreams: int | *0
+		connectionTimeout: string | *""
+		writeBufferSize: int | *0
+		readBufferSize: int | *0
+		initialWindowSize: int | *0
+		initialConnWindowSize: int | *0
+		maxConnectionIdle: string | *""
+		maxConnectionAge: string | *""
+		maxConnectionAgeGrace: string | *""
+		keepalive: {
+			time: string | *""
+			timeout: string | *""
+		}
+		enforcementPolicy: {
+			minTime: string | *""
+			permitWithoutStream: bool | *false
+		}
+	}
+	Health :: {
+		enabled: bool | *true
+		interval: string | *"10s"
+		shutdownDelay: string | *"0s"
+	}
+	Reflection :: {
+		enabled: bool | *false
+		identifier: string | *""
+	}
+	Server :: {
+		name: string
+		addr: string
+		socketMode: string | *"0660"
+		serveHTTP: bool | *false
+		drainTimeout: string | *"30s"
+		tls: TLS
+		server: ServerOptions
+		health: Health
+		reflection: Reflection
+		identifiers: *[] | [...string]
+	}
+
+	tls: TLS
+	server: ServerOptions
+	health: Health
+	reflection: Reflection
+	servers: *[] | [...Server]
+
+	Policy :: {
+		method: string
+		anonymous: bool | *false
+		identifiers: *[] | [...string]
+		realmRoles: *[] | [...string]
+		clientRoles: *{} | {...}
+		scopes: *[] | [...string]
+		expression: string | *""
+	}
+	policies: *[] | [...Policy]
+	policyDefault: *"allow" | "deny"
 }
 `
 }
 
-func buildIdentifier(
+// identifierSet holds the identifiers built from grpc.identifier and all problems found in the configuration
+type identifierSet struct {
+	identifiers []CallIdentifier
+	err         error
+}
+
+// buildIdentifierSet validates every grpc.identifier entry and builds the usable ones, all problems are reported together
+func buildIdentifierSet(
 	provider map[string]CallIdentifierFactory,
+	logger flamingo.Logger,
 	cfg *struct {
-		Config config.Slice `inject:"config:grpc.identifier"`
+		Config  config.Slice                    `inject:"config:grpc.identifier"`
+		Schemas map[string]CallIdentifierSchema `inject:",optional"`
 	},
-) []CallIdentifier {
-	var identifiers []config.Map
-	_ = cfg.Config.MapInto(&identifiers)
+) *identifierSet {
+	set := new(identifierSet)
 
-	res := make([]CallIdentifier, len(identifiers))
+	var identifiers []config.Map
+	if err := cfg.Config.MapInto(&identifiers); err != nil {
+		set.err = &ConfigError{Errors: []error{fmt.Errorf("grpc.identifier: %w", err)}}
+		logger.WithField(flamingo.LogKeyModule, "grpc").Error(set.err)
+		return set
+	}
 
-	var err error
+	var errs []error
+	seen := make(map[string]bool, len(identifiers))
 	for i, identifier := range identifiers {
+		name, _ := identifier["identifier"].(string)
+		if name == "" {
+			errs = append(errs, fmt.Errorf("grpc.identifier[%d]: no identifier set", i))
+		} else if seen[name] {
+			errs = append(errs, fmt.Errorf("grpc.identifier[%d]: identifier %q is configured more than once", i, name))
+		}
+		seen[name] = true
+
 		identityProvider, ok := identifier["provider"].(string)
-		if !ok {
-			panic("no provider set")
+		if !ok || identityProvider == "" {
+			errs = append(errs, fmt.Errorf("grpc.identifier[%d] %q: no provider set", i, name))
+			continue
 		}
 		factory, hasIt := provider[identityProvider]
 		if !hasIt {
-			panic("unknown identity provider " + identityProvider)
+			errs = append(errs, fmt.Errorf("grpc.identifier[%d] %q: unknown identity provider %q", i, name, identityProvider))
+			continue
+		}
+
+		if schema, hasSchema := cfg.Schemas[identityProvider]; hasSchema {
+			applied, err := schema.apply(identifier)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("grpc.identifier[%d] %q: %w", i, name, err))
+				continue
+			}
+			identifier = applied
 		}
 
-		res[i], err = factory(identifier)
+		callIdentifier, err := factory(identifier)
 		if err != nil {
-			panic(err)
+			errs = append(errs, fmt.Errorf("grpc.identifier[%d] %q: provider %s: %w", i, name, identityProvider, err))
+			continue
+		}
+		if callIdentifier == nil {
+			errs = append(errs, fmt.Errorf("grpc.identifier[%d] %q: can not build identity with provider %s", i, name, identityProvider))
+			continue
 		}
 
-		if res[i] == nil {
-			panic("can not build identity with provider " + identityProvider)
+		if loggingIdentifier, ok := callIdentifier.(loggingIdentifier); ok {
+			loggingIdentifier.setLogger(logger.WithField(flamingo.LogKeyModule, "grpc").WithField("identifier", name))
 		}
+
+		set.identifiers = append(set.identifiers, callIdentifier)
+	}
+
+	if len(errs) > 0 {
+		set.err = &ConfigError{Errors: errs}
+		logger.WithField(flamingo.LogKeyModule, "grpc").Error(set.err)
 	}
 
-	return res
+	return set
+}
+
+func buildIdentifier(set *identifierSet) []CallIdentifier {
+	return set.identifiers
 }
 
 type ServerModule struct{}
 
 func (*ServerModule) Configure(injector *dingo.Injector) {
-	flamingo.BindEventSubscriber(injector).To(new(grpcServer))
+	injector.Bind(new(grpcServers)).In(dingo.Singleton)
+	flamingo.BindEventSubscriber(injector).To(new(grpcServers))
+	injector.BindMulti(new(web.Filter)).To(new(httpFilter))
+	injector.BindMulti(new(cobra.Command)).ToProvider(grpcCommand)
+	injector.BindMulti(new(UnaryServerInterceptor)).ToInstance(UnaryServerInterceptor{
+		Order:       IdentityCacheOrder,
+		Interceptor: identityCacheUnaryInterceptor,
+	})
+	injector.BindMulti(new(StreamServerInterceptor)).ToInstance(StreamServerInterceptor{
+		Order:       IdentityCacheOrder,
+		Interceptor: identityCacheStreamInterceptor,
+	})
+	injector.Bind(new(policyEnforcer)).In(dingo.Singleton)
+	injector.BindMulti(new(UnaryServerInterceptor)).ToProvider(policyUnaryInterceptor)
+	injector.BindMulti(new(StreamServerInterceptor)).ToProvider(policyStreamInterceptor)
+	injector.Bind(new(annotationPolicies)).In(dingo.Singleton)
+	injector.BindMulti(new(UnaryServerInterceptor)).ToProvider(annotationUnaryInterceptor)
+	injector.BindMulti(new(StreamServerInterceptor)).ToProvider(annotationStreamInterceptor)
 }
 
 func (*ServerModule) Depends() []dingo.Module {
@@ -82,58 +315,3 @@ func (*ServerModule) Depends() []dingo.Module {
 		new(Module),
 	}
 }
-
-type grpcServer struct {
-	register   []ServerRegister
-	grpcServer *grpc.Server
-	addr       string
-}
-
-func (s *grpcServer) Inject(register []ServerRegister, config *struct {
-	Port string `inject:"config:grpc.addr"`
-}) {
-	s.register = register
-	s.addr = config.Port
-}
-
-func (s *grpcServer) Notify(ctx context.Context, event flamingo.Event) {
-	switch event.(type) {
-	case *flamingo.ServerStartEvent:
-		go func() {
-			if err := s.ServeTcpAddr(context.Background(), s.addr); err != nil {
-				log.Fatal(err)
-			}
-		}()
-	case *flamingo.ShutdownEvent:
-		if s.grpcServer != nil {
-			s.grpcServer.GracefulStop()
-		}
-	case *flamingo.ServerShutdownEvent:
-		if s.grpcServer != nil {
-			s.grpcServer.Stop()
-		}
-	}
-}
-
-func (s *grpcServer) ServeTcpAddr(ctx context.Context, addr string) error {
-	s.grpcServer = grpc.NewServer(grpc.StatsHandler(&ocgrpc.ServerHandler{
-		IsPublicEndpoint: false,
-		StartOptions: trace.StartOptions{
-			SpanKind: trace.SpanKindServer,
-			Sampler:  trace.AlwaysSample(),
-		},
-	}))
-
-	for _, rf := range s.register {
-		rf(s.grpcServer)
-	}
-
-	listener, err := net.Listen("tcp", addr)
-	if err != nil {
-		return fmt.Errorf("unable to listen: %w", err)
-	}
-
-	log.Printf("ready to listen on %s", addr)
-
-	return s.grpcServer.Serve(listener)
-}
@@ -17,17 +17,25 @@ import (
 type oauth2CallIdentifier struct {
 	identifier string
 	metakey    string
-	provider   *oidc.Provider
-	clientID   string
+	discovery  *oidcDiscovery
+	validation *accessTokenValidation
 }
 
 var _ CallIdentifier = new(oauth2CallIdentifier)
 
 type oidcConfig struct {
-	Identifier  string `json:"identifier"`
-	Issuer      string `json:"issuer"`
-	ClientID    string `json:"clientID"`
-	MetadataKey string `json:"metadatakey"`
+	Identifier        string          `json:"identifier"`
+	Issuer            string          `json:"issuer"`
+	ClientID          string          `json:"clientID"`
+	MetadataKey       string          `json:"metadatakey"`
+	Discovery         discoveryConfig `json:"discovery"`
+	Audiences         []string        `json:"audiences"`
+	SkipClientIDCheck bool            `json:"skipClientIDCheck"`
+	AuthorizedParties []string        `json:"authorizedParties"`
+	Scopes            []string        `json:"scopes"`
+	SigningAlgorithms []string        `json:"signingAlgorithms"`
+	ClockSkew         string          `json:"clockSkew"`
+	TokenType         string          `json:"tokenType"`
 }
 
 func oauth2Factory(cfg config.Map) (CallIdentifier, error) {
@@ -37,7 +45,12 @@ func oauth2Factory(cfg config.Map) (CallIdentifier, error) {
 		return nil, err
 	}
 
-	provider, err := oidc.NewProvider(context.Background(), oidcConfig.Issuer)
+	discovery, err := newOIDCDiscovery(oidcConfig.Issuer, oidcConfig.Discovery)
+	if err != nil {
+		return nil, err
+	}
+
+	validation, err := newAccessTokenValidation(oidcConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -48,8 +61,8 @@ func oauth2Factory(cfg config.Map) (CallIdentifier, error) {
 
 	return &oauth2CallIdentifier{
 		identifier: oidcConfig.Identifier,
-		provider:   provider,
-		clientID:   oidcConfig.ClientID,
+		discovery:  discovery,
+		validation: validation,
 		metakey:    oidcConfig.MetadataKey,
 	}, nil
 }
@@ -102,20 +115,30 @@ func (identity *oauth2Identity) AccessTokenClaims(into interface{}) error {
 func (identifier *oauth2CallIdentifier) Identify(ctx context.Context) (auth.Identity, error) {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return nil, fmt.Errorf("no metadata available")
+		return nil, identificationError(IdentificationNotPresent, "no metadata available")
 	}
 
-	verifier := identifier.provider.Verifier(&oidc.Config{
-		ClientID: identifier.clientID,
-	})
+	rawTokens := md.Get(identifier.metakey)
+	if len(rawTokens) == 0 {
+		return nil, identificationError(IdentificationNotPresent, "no token in metadata %q", identifier.metakey)
+	}
 
-	var err error
-	var token *oidc.IDToken
-	for _, rawToken := range md.Get(identifier.metakey) {
-		if strings.HasPrefix(strings.ToLower(rawToken), "bearer ") {
-			rawToken = rawToken[len("bearer "):]
+	provider, err := identifier.discovery.get()
+	if err != nil {
+		return nil, err
+	}
+
+	verifier := provider.Verifier(identifier.validation.verifierConfig())
+
+	errs := make([]error, 0, len(rawTokens))
+	for _, rawToken := range rawTokens {
+		rawToken = bearerToken(rawToken)
+		token, err := verifier.Verify(ctx, rawToken)
+		if err != nil {
+			err = oidcVerifyError(err)
+		} else {
+			err = identifier.validation.check(token, rawToken)
 		}
-		token, err = verifier.Verify(ctx, rawToken)
 		if err == nil {
 			return &oauth2Identity{
 				identifier: identifier.identifier,
@@ -123,7 +146,16 @@ func (identifier *oauth2CallIdentifier) Identify(ctx context.Context) (auth.Iden
 				rawToken:   rawToken,
 			}, nil
 		}
+		errs = append(errs, err)
 	}
 
-	return nil, fmt.Errorf("can not identify call, last error: %#w", err)
+	return nil, newTokenErrors(errs)
+}
+
+// bearerToken strips the optional bearer prefix of a token from the metadata
+func bearerToken(rawToken string) string {
+	if strings.HasPrefix(strings.ToLower(rawToken), "bearer ") {
+		return rawToken[len("bearer "):]
+	}
+	return rawToken
 }
@@ -0,0 +1,182 @@
+package grpc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// testIssuer is an OpenID provider serving the discovery document and the signing keys, both can be made to fail
+type testIssuer struct {
+	*httptest.Server
+	key            *rsa.PrivateKey
+	discoveryFails int32
+	keysFail       int32
+	discoveries    int32
+	keyDownloads   int32
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := &testIssuer{key: key}
+	issuer.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			atomic.AddInt32(&issuer.discoveries, 1)
+			if atomic.LoadInt32(&issuer.discoveryFails) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"issuer":                 issuer.URL,
+				"jwks_uri":               issuer.URL + "/certs",
+				"authorization_endpoint": issuer.URL + "/auth",
+				"token_endpoint":         issuer.URL + "/token",
+			})
+		case "/certs":
+			atomic.AddInt32(&issuer.keyDownloads, 1)
+			if atomic.LoadInt32(&issuer.keysFail) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+				{Key: &key.PublicKey, Algorithm: string(jose.RS256), Use: "sig"},
+			}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(issuer.Close)
+
+	return issuer
+}
+
+func (issuer *testIssuer) token(t *testing.T, overrides map[string]interface{}) string {
+	t.Helper()
+
+	claims := map[string]interface{}{
+		"iss": issuer.URL,
+		"sub": "user",
+		"aud": "api",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for name, value := range overrides {
+		claims[name] = value
+	}
+	return signAccessToken(t, issuer.key, "", claims)
+}
+
+func TestOauth2CallIdentifier_Identify(t *testing.T) {
+	type tokens func(t *testing.T, issuer *testIssuer) []string
+
+	tests := []struct {
+		name         string
+		keysFail     bool
+		tokens       tokens
+		want         IdentificationStatus
+		wantCode     codes.Code
+		wantMessages []string
+	}{
+		{
+			name:   "no token",
+			tokens: func(*testing.T, *testIssuer) []string { return nil },
+			want:   IdentificationNotPresent, wantCode: codes.Unknown,
+		},
+		{
+			name: "valid token",
+			tokens: func(t *testing.T, issuer *testIssuer) []string {
+				return []string{"Bearer " + issuer.token(t, nil)}
+			},
+			want: IdentificationOK, wantCode: codes.OK,
+		},
+		{
+			name: "expired token",
+			tokens: func(t *testing.T, issuer *testIssuer) []string {
+				return []string{"Bearer " + issuer.token(t, map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()})}
+			},
+			want: IdentificationExpired, wantCode: codes.Unknown,
+			wantMessages: []string{"can not identify call: token is expired"},
+		},
+		{
+			name: "valid token after rejected one",
+			tokens: func(t *testing.T, issuer *testIssuer) []string {
+				return []string{"Bearer invalid", "Bearer " + issuer.token(t, nil)}
+			},
+			want: IdentificationOK, wantCode: codes.OK,
+		},
+		{
+			name: "every token is reported",
+			tokens: func(t *testing.T, issuer *testIssuer) []string {
+				return []string{
+					"Bearer " + issuer.token(t, map[string]interface{}{"aud": "other"}),
+					"Bearer " + issuer.token(t, map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()}),
+				}
+			},
+			want: IdentificationWrongAudience, wantCode: codes.Unknown,
+			wantMessages: []string{"token 1: expected audience", "token 2: token is expired"},
+		},
+		{
+			name:     "keys can not be fetched",
+			keysFail: true,
+			tokens: func(t *testing.T, issuer *testIssuer) []string {
+				return []string{"Bearer invalid", "Bearer " + issuer.token(t, nil)}
+			},
+			want: IdentificationUnavailable, wantCode: codes.Unavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newTestIssuer(t)
+			if tt.keysFail {
+				atomic.StoreInt32(&issuer.keysFail, 1)
+			}
+
+			discovery, err := newOIDCDiscovery(issuer.URL, discoveryConfig{Timeout: "5s"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			validation, err := newAccessTokenValidation(oidcConfig{ClientID: "api"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			identifier := &oauth2CallIdentifier{identifier: "keycloak", metakey: "authorization", discovery: discovery, validation: validation}
+
+			md := metadata.MD{}
+			for _, token := range tt.tokens(t, issuer) {
+				md.Append("authorization", token)
+			}
+			identity, err := identifier.Identify(metadata.NewIncomingContext(context.Background(), md))
+
+			if got := identificationStatus(identity, err); got != tt.want {
+				t.Errorf("Identify() = %v (%v), want %v", got, err, tt.want)
+			}
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("status.Code() = %v, want %v", got, tt.wantCode)
+			}
+			for _, message := range tt.wantMessages {
+				if err == nil || !strings.Contains(err.Error(), message) {
+					t.Errorf("error %v does not contain %q", err, message)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package grpc
+
+import (
+	"fmt"
+	"time"
+
+	"flamingo.me/flamingo/v3/framework/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+type serverOptionsConfig struct {
+	MaxRecvMsgSize        int    `json:"maxRecvMsgSize"`
+	MaxSendMsgSize        int    `json:"maxSendMsgSize"`
+	MaxConcurrentStreams  uint32 `json:"maxConcurrentStreams"`
+	ConnectionTimeout     string `json:"connectionTimeout"`
+	WriteBufferSize       int    `json:"writeBufferSize"`
+	ReadBufferSize        int    `json:"readBufferSize"`
+	InitialWindowSize     int32  `json:"initialWindowSize"`
+	InitialConnWindowSize int32  `json:"initialConnWindowSize"`
+	MaxConnectionIdle     string `json:"maxConnectionIdle"`
+	MaxConnectionAge      string `json:"maxConnectionAge"`
+	MaxConnectionAgeGrace string `json:"maxConnectionAgeGrace"`
+	Keepalive             struct {
+		Time    string `json:"time"`
+		Timeout string `json:"timeout"`
+	} `json:"keepalive"`
+	EnforcementPolicy struct {
+		MinTime             string `json:"minTime"`
+		PermitWithoutStream bool   `json:"permitWithoutStream"`
+	} `json:"enforcementPolicy"`
+}
+
+// duration parses an optional duration, an empty string results in 0 which means the grpc default is used
+func duration(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration for %s: %w", name, err)
+	}
+
+	return d, nil
+}
+
+// serverOptions translates the grpc.server configuration into grpc server options, unset values keep the grpc defaults
+func serverOptions(cfg config.Map) ([]grpc.ServerOption, error) {
+	var serverCfg serverOptionsConfig
+	if err := cfg.MapInto(&serverCfg); err != nil {
+		return nil, err
+	}
+
+	var options []grpc.ServerOption
+
+	if serverCfg.MaxRecvMsgSize > 0 {
+		options = append(options, grpc.MaxRecvMsgSize(serverCfg.MaxRecvMsgSize))
+	}
+	if serverCfg.MaxSendMsgSize > 0 {
+		options = append(options, grpc.MaxSendMsgSize(serverCfg.MaxSendMsgSize))
+	}
+	if serverCfg.MaxConcurrentStreams > 0 {
+		options = append(options, grpc.MaxConcurrentStreams(serverCfg.MaxConcurrentStreams))
+	}
+	if serverCfg.WriteBufferSize > 0 {
+		options = append(options, grpc.WriteBufferSize(serverCfg.WriteBufferSize))
+	}
+	if serverCfg.ReadBufferSize > 0 {
+		options = append(options, grpc.ReadBufferSize(serverCfg.ReadBufferSize))
+	}
+	if serverCfg.InitialWindowSize > 0 {
+		options = append(options, grpc.InitialWindowSize(serverCfg.InitialWindowSize))
+	}
+	if serverCfg.InitialConnWindowSize > 0 {
+		options = append(options, grpc.InitialConnWindowSize(serverCfg.InitialConnWindowSize))
+	}
+
+	connectionTimeout, err := duration("connectionTimeout", serverCfg.ConnectionTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if connectionTimeout > 0 {
+		options = append(options, grpc.ConnectionTimeout(connectionTimeout))
+	}
+
+	var params keepalive.ServerParameters
+	for _, d := range []struct {
+		name  string
+		value string
+		into  *time.Duration
+	}{
+		{"maxConnectionIdle", serverCfg.MaxConnectionIdle, &params.MaxConnectionIdle},
+		{"maxConnectionAge", serverCfg.MaxConnectionAge, &params.MaxConnectionAge},
+		{"maxConnectionAgeGrace", serverCfg.MaxConnectionAgeGrace, &params.MaxConnectionAgeGrace},
+		{"keepalive.time", serverCfg.Keepalive.Time, &params.Time},
+		{"keepalive.timeout", serverCfg.Keepalive.Timeout, &params.Timeout},
+	} {
+		if *d.into, err = duration(d.name, d.value); err != nil {
+			return nil, err
+		}
+	}
+	if params != (keepalive.ServerParameters{}) {
+		options = append(options, grpc.KeepaliveParams(params))
+	}
+
+	minTime, err := duration("enforcementPolicy.minTime", serverCfg.EnforcementPolicy.MinTime)
+	if err != nil {
+		return nil, err
+	}
+	if minTime > 0 || serverCfg.EnforcementPolicy.PermitWithoutStream {
+		options = append(options, grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             minTime,
+			PermitWithoutStream: serverCfg.EnforcementPolicy.PermitWithoutStream,
+		}))
+	}
+
+	return options, nil
+}
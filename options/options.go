@@ -0,0 +1,5 @@
+// Package options provides the flamingo.grpc.auth proto options to annotate services and methods with authorization rules
+package options
+
+//go:generate go install google.golang.org/protobuf/cmd/protoc-gen-go
+//go:generate protoc --go_out=.. --go_opt=module=flamingo.me/grpc -I ../proto ../proto/flamingo/grpc/options/auth.proto
@@ -0,0 +1,266 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"flamingo.me/flamingo/v3/core/auth"
+	"flamingo.me/flamingo/v3/core/auth/oauth"
+	"flamingo.me/flamingo/v3/framework/config"
+	"github.com/google/cel-go/cel"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PolicyOrder is the order of the interceptors enforcing grpc.policies, it runs after the identity cache is attached
+const PolicyOrder = -900
+
+type (
+	policyConfig struct {
+		Method      string              `json:"method"`
+		Anonymous   bool                `json:"anonymous"`
+		Identifiers []string            `json:"identifiers"`
+		RealmRoles  []string            `json:"realmRoles"`
+		ClientRoles map[string][]string `json:"clientRoles"`
+		Scopes      []string            `json:"scopes"`
+		Expression  string              `json:"expression"`
+
+		program cel.Program
+	}
+
+	// policyEnforcer authorizes calls based on the first grpc.policies entry matching the called method
+	policyEnforcer struct {
+		identityService *IdentityService
+		policies        []policyConfig
+		deny            bool
+		err             error
+	}
+)
+
+func (e *policyEnforcer) Inject(identityService *IdentityService, identifiers *identifierSet, cfg *struct {
+	Policies config.Slice `inject:"config:grpc.policies"`
+	Default  string       `inject:"config:grpc.policyDefault"`
+}) *policyEnforcer {
+	e.identityService = identityService
+	e.deny = cfg.Default == "deny"
+
+	if err := cfg.Policies.MapInto(&e.policies); err != nil {
+		e.err = fmt.Errorf("grpc.policies: %w", err)
+		return e
+	}
+
+	known := make(map[string]bool, len(identifiers.identifiers))
+	for _, identifier := range identifiers.identifiers {
+		known[identifier.Identifier()] = true
+	}
+
+	var errs []error
+	for i, policy := range e.policies {
+		if _, err := path.Match(policy.Method, "/"); err != nil {
+			errs = append(errs, fmt.Errorf("grpc.policies[%d]: invalid method pattern %q: %w", i, policy.Method, err))
+		}
+		for _, identifier := range policy.Identifiers {
+			if !known[identifier] {
+				errs = append(errs, fmt.Errorf("grpc.policies[%d]: unknown identifier %q", i, identifier))
+			}
+		}
+		if policy.Expression != "" {
+			program, err := compileExpression(policy.Expression)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("grpc.policies[%d]: invalid expression: %w", i, err))
+				continue
+			}
+			e.policies[i].program = program
+		}
+	}
+	if len(errs) > 0 {
+		e.err = &ConfigError{Errors: errs}
+	}
+
+	return e
+}
+
+func (e *policyEnforcer) policyFor(fullMethod string) *policyConfig {
+	for i := range e.policies {
+		if matched, _ := path.Match(e.policies[i].Method, fullMethod); matched {
+			return &e.policies[i]
+		}
+	}
+	return nil
+}
+
+// authorize returns codes.Unauthenticated if the call can not be identified and codes.PermissionDenied if no identity fulfills the policy.
+// The policy expression is only evaluated if a request message is given.
+func (e *policyEnforcer) authorize(ctx context.Context, fullMethod string, request interface{}) error {
+	policy := e.policyFor(fullMethod)
+	if policy == nil {
+		if e.deny {
+			return status.Errorf(codes.PermissionDenied, "no policy for %s", fullMethod)
+		}
+		return nil
+	}
+
+	return e.enforce(ctx, policy, fullMethod, request)
+}
+
+// enforce checks the identities of the call and, if a request message is given, the policy expression
+func (e *policyEnforcer) enforce(ctx context.Context, policy *policyConfig, fullMethod string, request interface{}) error {
+	if policy.Anonymous {
+		return nil
+	}
+
+	identities, unavailable := e.identities(ctx, policy)
+	if len(identities) == 0 {
+		if unavailable != nil {
+			return unavailable
+		}
+		return status.Error(codes.Unauthenticated, "call can not be identified")
+	}
+
+	for _, identity := range identities {
+		if policy.fulfilledBy(identity) && (request == nil || policy.allows(ctx, identity, fullMethod, request)) {
+			return nil
+		}
+	}
+
+	return status.Errorf(codes.PermissionDenied, "permission denied for %s", fullMethod)
+}
+
+// identities returns the identities of the call allowed by the policy, and the error of an identifier which is unavailable
+func (e *policyEnforcer) identities(ctx context.Context, policy *policyConfig) ([]auth.Identity, error) {
+	var identities []auth.Identity
+	var unavailable error
+	for _, provider := range e.identityService.providersFor(ctx) {
+		if len(policy.Identifiers) > 0 && !contains(policy.Identifiers, provider.Identifier()) {
+			continue
+		}
+
+		identity, err := e.identityService.identify(ctx, provider)
+		if identity != nil {
+			identities = append(identities, identity)
+		} else if status.Code(err) == codes.Unavailable {
+			unavailable = err
+		}
+	}
+	return identities, unavailable
+}
+
+func (policy *policyConfig) fulfilledBy(identity auth.Identity) bool {
+	if len(policy.RealmRoles) == 0 && len(policy.ClientRoles) == 0 && len(policy.Scopes) == 0 {
+		return true
+	}
+
+	oauthIdentity, ok := identity.(oauth.Identity)
+	if !ok {
+		return false
+	}
+
+	if len(policy.RealmRoles) > 0 {
+		roles, err := KeycloakRealmRoles(oauthIdentity)
+		if err != nil || !containsAll(roles, policy.RealmRoles) {
+			return false
+		}
+	}
+
+	for client, required := range policy.ClientRoles {
+		roles, err := KeycloakClientRoles(oauthIdentity, client)
+		if err != nil || !containsAll(roles, required) {
+			return false
+		}
+	}
+
+	if len(policy.Scopes) > 0 {
+		scopes, err := TokenScopes(oauthIdentity)
+		if err != nil || !containsAll(scopes, policy.Scopes) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// TokenScopes returns the scopes of the access token, read from the space separated "scope" claim or the "scp" claim
+func TokenScopes(token oauth.Identity) ([]string, error) {
+	var claims struct {
+		Scope string          `json:"scope"`
+		Scp   json.RawMessage `json:"scp"`
+	}
+	if err := token.AccessTokenClaims(&claims); err != nil {
+		return nil, err
+	}
+
+	scopes := strings.Fields(claims.Scope)
+	if len(claims.Scp) > 0 {
+		var list []string
+		if err := json.Unmarshal(claims.Scp, &list); err == nil {
+			scopes = append(scopes, list...)
+		} else {
+			var single string
+			if err := json.Unmarshal(claims.Scp, &single); err == nil {
+				scopes = append(scopes, strings.Fields(single)...)
+			}
+		}
+	}
+
+	return scopes, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAll(values []string, required []string) bool {
+	present := make(map[string]bool, len(values))
+	for _, value := range values {
+		present[value] = true
+	}
+	for _, value := range required {
+		if !present[value] {
+			return false
+		}
+	}
+	return true
+}
+
+func policyUnaryInterceptor(enforcer *policyEnforcer) UnaryServerInterceptor {
+	return UnaryServerInterceptor{
+		Order: PolicyOrder,
+		Interceptor: func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := enforcer.authorize(ctx, info.FullMethod, req); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		},
+	}
+}
+
+func policyStreamInterceptor(enforcer *policyEnforcer) StreamServerInterceptor {
+	return StreamServerInterceptor{
+		Order: PolicyOrder,
+		Interceptor: func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := enforcer.authorize(ss.Context(), info.FullMethod, nil); err != nil {
+				return err
+			}
+
+			if policy := enforcer.policyFor(info.FullMethod); policy != nil && policy.program != nil {
+				ss = &expressionServerStream{
+					ServerStream: ss,
+					check: func(request interface{}) error {
+						return enforcer.enforce(ss.Context(), policy, info.FullMethod, request)
+					},
+				}
+			}
+
+			return handler(srv, ss)
+		},
+	}
+}
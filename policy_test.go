@@ -0,0 +1,165 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"flamingo.me/flamingo/v3/core/auth"
+	"flamingo.me/flamingo/v3/framework/config"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// staticCallIdentifier returns a fixed identity or error, for tests
+type staticCallIdentifier struct {
+	identifier string
+	identity   auth.Identity
+	err        error
+}
+
+func (identifier *staticCallIdentifier) Identifier() string {
+	return identifier.identifier
+}
+
+func (identifier *staticCallIdentifier) Identify(context.Context) (auth.Identity, error) {
+	return identifier.identity, identifier.err
+}
+
+func newTestEnforcer(t *testing.T, defaultPolicy string, policies config.Slice, identifiers ...CallIdentifier) *policyEnforcer {
+	t.Helper()
+
+	enforcer := new(policyEnforcer).Inject(
+		&IdentityService{identityProviders: identifiers},
+		&identifierSet{identifiers: []CallIdentifier{
+			&staticCallIdentifier{identifier: "management"},
+			&staticCallIdentifier{identifier: "customers"},
+		}},
+		&struct {
+			Policies config.Slice `inject:"config:grpc.policies"`
+			Default  string       `inject:"config:grpc.policyDefault"`
+		}{Policies: policies, Default: defaultPolicy},
+	)
+	if enforcer.err != nil {
+		t.Fatalf("unexpected config error: %v", enforcer.err)
+	}
+
+	return enforcer
+}
+
+func TestPolicyEnforcer_authorize(t *testing.T) {
+	admin := &staticCallIdentifier{identifier: "management", identity: &mockIdentity{
+		identifier: "management",
+		subject:    "admin",
+		claims: []byte(`{
+			"scope": "read write",
+			"customer_id": "42",
+			"realm_access": {"roles": ["admin"]},
+			"resource_access": {"sampleapp": {"roles": ["editor"]}}
+		}`),
+	}}
+	customer := &staticCallIdentifier{identifier: "customers", identity: &mockIdentity{
+		identifier: "customers",
+		subject:    "customer",
+		claims:     []byte(`{"scp": ["read"], "customer_id": "7"}`),
+	}}
+	reader := &staticCallIdentifier{identifier: "management", identity: &mockIdentity{
+		identifier: "management",
+		subject:    "reader",
+		claims:     []byte(`{"scope": "read"}`),
+	}}
+	anonymous := &staticCallIdentifier{identifier: "management", err: errors.New("no token")}
+	unavailable := &staticCallIdentifier{identifier: "management", err: status.Error(codes.Unavailable, "discovery pending")}
+
+	policies := config.Slice{
+		config.Map{"method": "/test.Service/Public", "anonymous": true},
+		config.Map{"method": "/test.Service/Read", "scopes": config.Slice{"read"}},
+		config.Map{"method": "/test.Service/Write", "identifiers": config.Slice{"management"}, "scopes": config.Slice{"write"}},
+		config.Map{"method": "/test.Service/Admin", "realmRoles": config.Slice{"admin"}, "clientRoles": config.Map{"sampleapp": config.Slice{"editor"}}},
+		config.Map{"method": "/test.Service/Customer", "expression": `request.customer_id == identity.claims.customer_id`},
+		config.Map{"method": "/test.Service/Metadata", "expression": `"x-tenant" in metadata && metadata["x-tenant"][0] == "acme" && method.endsWith("/Metadata")`},
+	}
+
+	customerRequest := func(id string) interface{} {
+		request, err := structpb.NewStruct(map[string]interface{}{"customer_id": id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return request
+	}
+
+	tests := []struct {
+		name          string
+		defaultPolicy string
+		identifiers   []CallIdentifier
+		method        string
+		request       interface{}
+		metadata      metadata.MD
+		want          codes.Code
+	}{
+		{name: "anonymous method without identity", identifiers: []CallIdentifier{anonymous}, method: "/test.Service/Public", want: codes.OK},
+		{name: "no policy, default allow", identifiers: []CallIdentifier{anonymous}, method: "/test.Service/Other", want: codes.OK},
+		{name: "no policy, default deny", defaultPolicy: "deny", identifiers: []CallIdentifier{admin}, method: "/test.Service/Other", want: codes.PermissionDenied},
+		{name: "policy without identity", identifiers: []CallIdentifier{anonymous}, method: "/test.Service/Read", want: codes.Unauthenticated},
+		{name: "scope from scope claim", identifiers: []CallIdentifier{admin}, method: "/test.Service/Read", want: codes.OK},
+		{name: "scope from scp claim", identifiers: []CallIdentifier{customer}, method: "/test.Service/Read", want: codes.OK},
+		{name: "missing scope", identifiers: []CallIdentifier{reader}, method: "/test.Service/Write", want: codes.PermissionDenied},
+		{name: "identity of the allowed identifier is used", identifiers: []CallIdentifier{customer, admin}, method: "/test.Service/Write", want: codes.OK},
+		{name: "identity of other identifier is ignored", identifiers: []CallIdentifier{customer}, method: "/test.Service/Write", want: codes.Unauthenticated},
+		{name: "realm and client roles", identifiers: []CallIdentifier{admin}, method: "/test.Service/Admin", want: codes.OK},
+		{name: "missing roles", identifiers: []CallIdentifier{customer}, method: "/test.Service/Admin", want: codes.PermissionDenied},
+		{name: "expression matches", identifiers: []CallIdentifier{customer}, method: "/test.Service/Customer", request: customerRequest("7"), want: codes.OK},
+		{name: "expression matches second identity", identifiers: []CallIdentifier{customer, admin}, method: "/test.Service/Customer", request: customerRequest("42"), want: codes.OK},
+		{name: "expression rejects", identifiers: []CallIdentifier{customer}, method: "/test.Service/Customer", request: customerRequest("42"), want: codes.PermissionDenied},
+		{name: "expression is skipped without request", identifiers: []CallIdentifier{customer}, method: "/test.Service/Customer", want: codes.OK},
+		{name: "expression on metadata", identifiers: []CallIdentifier{customer}, method: "/test.Service/Metadata", request: customerRequest("1"), metadata: metadata.Pairs("x-tenant", "acme"), want: codes.OK},
+		{name: "expression on missing metadata", identifiers: []CallIdentifier{customer}, method: "/test.Service/Metadata", request: customerRequest("1"), want: codes.PermissionDenied},
+		{name: "unavailable identifier", identifiers: []CallIdentifier{unavailable}, method: "/test.Service/Read", want: codes.Unavailable},
+		{name: "unavailable identifier, anonymous method", identifiers: []CallIdentifier{unavailable}, method: "/test.Service/Public", want: codes.OK},
+		{name: "unavailable identifier, other identity", identifiers: []CallIdentifier{unavailable, customer}, method: "/test.Service/Read", want: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enforcer := newTestEnforcer(t, tt.defaultPolicy, policies, tt.identifiers...)
+
+			ctx := context.Background()
+			if tt.metadata != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.metadata)
+			}
+
+			err := enforcer.authorize(ctx, tt.method, tt.request)
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("authorize() = %v (%v), want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyEnforcer_Inject(t *testing.T) {
+	tests := []struct {
+		name     string
+		policies config.Slice
+	}{
+		{name: "invalid pattern", policies: config.Slice{config.Map{"method": "/test.Service/["}}},
+		{name: "unknown identifier", policies: config.Slice{config.Map{"method": "/*/*", "identifiers": config.Slice{"unknown"}}}},
+		{name: "invalid expression", policies: config.Slice{config.Map{"method": "/*/*", "expression": "identity.subject =="}}},
+		{name: "expression not bool", policies: config.Slice{config.Map{"method": "/*/*", "expression": "identity.subject"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enforcer := new(policyEnforcer).Inject(new(IdentityService), new(identifierSet), &struct {
+				Policies config.Slice `inject:"config:grpc.policies"`
+				Default  string       `inject:"config:grpc.policyDefault"`
+			}{Policies: tt.policies})
+
+			var configErr *ConfigError
+			if !errors.As(enforcer.err, &configErr) {
+				t.Errorf("expected a ConfigError, got %v", enforcer.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package grpc
+
+import (
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// ReflectionGuardOrder is the order of the interceptor guarding reflection, it uses the identity cache and runs before the policies
+const ReflectionGuardOrder = -950
+
+// reflectionService is the only reflection version available with this grpc version
+const reflectionService = "grpc.reflection.v1alpha.ServerReflection"
+
+type reflectionConfig struct {
+	Enabled    bool   `json:"enabled"`
+	Identifier string `json:"identifier"`
+}
+
+func registerReflection(server *grpc.Server) {
+	reflection.Register(server)
+}
+
+func isReflectionMethod(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/"+reflectionService+"/")
+}
+
+// reflectionGuard rejects reflection calls which can not be identified by the given identifier
+func reflectionGuard(identityService *IdentityService, identifier string) StreamServerInterceptor {
+	return StreamServerInterceptor{
+		Order: ReflectionGuardOrder,
+		Interceptor: func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if !isReflectionMethod(info.FullMethod) {
+				return handler(srv, ss)
+			}
+
+			if identity, err := identityService.IdentifyFor(ss.Context(), identifier); identity == nil || err != nil {
+				return status.Error(codes.Unauthenticated, "reflection requires identification")
+			}
+
+			return handler(srv, ss)
+		},
+	}
+}
@@ -0,0 +1,94 @@
+package grpc
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"flamingo.me/flamingo/v3/core/auth"
+	"flamingo.me/flamingo/v3/framework/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// countingCallIdentifier counts how often it is asked to identify a call
+type countingCallIdentifier struct {
+	staticCallIdentifier
+	calls int
+}
+
+func (identifier *countingCallIdentifier) Identify(ctx context.Context) (auth.Identity, error) {
+	identifier.calls++
+	return identifier.staticCallIdentifier.Identify(ctx)
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// runStreamInterceptors calls the interceptors in the order of chainStreamInterceptors
+func runStreamInterceptors(interceptors []StreamServerInterceptor, fullMethod string) error {
+	sort.SliceStable(interceptors, func(i, j int) bool {
+		return interceptors[i].Order < interceptors[j].Order
+	})
+
+	handler := func(interface{}, grpc.ServerStream) error { return nil }
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i].Interceptor, handler
+		handler = func(srv interface{}, ss grpc.ServerStream) error {
+			return interceptor(srv, ss, &grpc.StreamServerInfo{FullMethod: fullMethod}, next)
+		}
+	}
+
+	return handler(nil, &testServerStream{ctx: context.Background()})
+}
+
+func TestReflectionGuard(t *testing.T) {
+	reflectionMethod := "/" + reflectionService + "/ServerReflectionInfo"
+
+	tests := []struct {
+		name      string
+		identity  auth.Identity
+		method    string
+		want      codes.Code
+		wantCalls int
+	}{
+		{name: "identified once for guard and policy", identity: &mockIdentity{identifier: "management", subject: "developer", claims: []byte(`{}`)}, method: reflectionMethod, want: codes.OK, wantCalls: 1},
+		{name: "not identified", method: reflectionMethod, want: codes.Unauthenticated, wantCalls: 1},
+		{name: "other methods are not guarded", method: "/test.Service/Other", want: codes.OK, wantCalls: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identifier := &countingCallIdentifier{staticCallIdentifier: staticCallIdentifier{identifier: "management", identity: tt.identity}}
+			if tt.identity == nil {
+				identifier.err = status.Error(codes.Unauthenticated, "no token")
+			}
+			identityService := &IdentityService{identityProviders: []CallIdentifier{identifier}}
+
+			enforcer := new(policyEnforcer).Inject(identityService, &identifierSet{identifiers: []CallIdentifier{identifier}}, &struct {
+				Policies config.Slice `inject:"config:grpc.policies"`
+				Default  string       `inject:"config:grpc.policyDefault"`
+			}{Policies: config.Slice{config.Map{"method": "/" + reflectionService + "/*", "identifiers": config.Slice{"management"}}}})
+
+			err := runStreamInterceptors([]StreamServerInterceptor{
+				policyStreamInterceptor(enforcer),
+				reflectionGuard(identityService, "management"),
+				{Order: IdentityCacheOrder, Interceptor: identityCacheStreamInterceptor},
+			}, tt.method)
+
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("got %v (%v), want %v", got, err, tt.want)
+			}
+			if identifier.calls != tt.wantCalls {
+				t.Errorf("identifier was asked %d times, want %d", identifier.calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,650 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"flamingo.me/flamingo/v3/core/healthcheck/domain/healthcheck"
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"go.opencensus.io/plugin/ocgrpc"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc"
+)
+
+// DefaultServer is the name of the server configured by grpc.addr, plain ServerRegister bindings are registered there
+const DefaultServer = "default"
+
+// errServerStopped is returned by build if the server was stopped before it was started
+var errServerStopped = errors.New("grpc server stopped")
+
+type (
+	// GrpcServerStartedEvent is dispatched when a grpc server is ready to accept calls
+	GrpcServerStartedEvent struct {
+		Server string
+		Addr   string
+	}
+
+	// GrpcServerStartupFailedEvent is dispatched when a grpc server can not be started, Server is empty for configuration errors
+	GrpcServerStartupFailedEvent struct {
+		Server string
+		Err    error
+	}
+
+	// GrpcServerStoppedEvent is dispatched when a grpc server has been stopped and all calls are finished or aborted
+	GrpcServerStoppedEvent struct {
+		Server string
+	}
+
+	// NamedServerRegister is multibound to register services on a named server only
+	//
+	//	injector.BindMulti(new(grpc.NamedServerRegister)).ToProvider(func(s *Impl) grpc.NamedServerRegister {
+	//		return grpc.NamedServerRegister{Server: "internal", Register: ...}
+	//	})
+	NamedServerRegister struct {
+		Server   string
+		Register ServerRegister
+	}
+
+	serverConfig struct {
+		Name         string     `json:"name"`
+		Addr         string     `json:"addr"`
+		SocketMode   string     `json:"socketMode"`
+		ServeHTTP    bool       `json:"serveHTTP"`
+		DrainTimeout string     `json:"drainTimeout"`
+		TLS          config.Map `json:"tls"`
+		Server       config.Map `json:"server"`
+		Health       config.Map `json:"health"`
+		Reflection   config.Map `json:"reflection"`
+		Identifiers  []string   `json:"identifiers"`
+	}
+
+	// grpcServers starts and stops all configured servers along with the flamingo server lifecycle
+	grpcServers struct {
+		servers       []*grpcServer
+		err           error
+		eventRouter   flamingo.EventRouter
+		logger        flamingo.Logger
+		exitOnFailure bool
+		shutdownOnce  sync.Once
+
+		mu       sync.RWMutex
+		http     *grpc.Server
+		started  bool
+		failures chan error
+	}
+
+	grpcServer struct {
+		config       serverConfig
+		drainTimeout time.Duration
+		eventRouter  flamingo.EventRouter
+		logger       flamingo.Logger
+		stopOnce     sync.Once
+		register     []ServerRegister
+		identity     *IdentityService
+		status       map[string]healthcheck.Status
+		unary        []UnaryServerInterceptor
+		stream       []StreamServerInterceptor
+		tls          *certReloader
+		listener     net.Listener
+		identifiers  map[string]bool
+
+		// mu guards the fields set by build on the serving goroutine and read by the stop functions
+		mu         sync.Mutex
+		grpcServer *grpc.Server
+		health     *healthService
+		shutdown   bool
+		draining   chan struct{}
+
+		options        []grpc.ServerOption
+		reflection     reflectionConfig
+		healthInterval time.Duration
+		healthDelay    time.Duration
+	}
+
+	serverInfoKey struct{}
+
+	serverInfo struct {
+		name        string
+		identifiers []string
+	}
+
+	contextServerStream struct {
+		grpc.ServerStream
+		ctx context.Context
+	}
+)
+
+func (s *grpcServers) Inject(
+	register []ServerRegister,
+	identityService *IdentityService,
+	identifiers *identifierSet,
+	policies *policyEnforcer,
+	eventRouter flamingo.EventRouter,
+	logger flamingo.Logger,
+	config *struct {
+		Addr                 string                        `inject:"config:grpc.addr"`
+		ServeHTTP            bool                          `inject:"config:grpc.serveHTTP"`
+		DrainTimeout         string                        `inject:"config:grpc.drainTimeout"`
+		SocketMode           string                        `inject:"config:grpc.socketMode"`
+		ExitOnStartupFailure bool                          `inject:"config:grpc.exitOnStartupFailure"`
+		TLS                  config.Map                    `inject:"config:grpc.tls"`
+		Options              config.Map                    `inject:"config:grpc.server"`
+		Health               config.Map                    `inject:"config:grpc.health"`
+		Reflection           config.Map                    `inject:"config:grpc.reflection"`
+		Servers              config.Slice                  `inject:"config:grpc.servers"`
+		NamedRegister        []NamedServerRegister         `inject:",optional"`
+		Listeners            []ServerListener              `inject:",optional"`
+		Status               map[string]healthcheck.Status `inject:",optional"`
+		UnaryInterceptors    []UnaryServerInterceptor      `inject:",optional"`
+		StreamInterceptors   []StreamServerInterceptor     `inject:",optional"`
+	},
+) {
+	s.eventRouter = eventRouter
+	s.logger = logger.WithField(flamingo.LogKeyModule, "grpc")
+	s.exitOnFailure = config.ExitOnStartupFailure
+
+	var errs []error
+	if identifiers.err != nil {
+		errs = append(errs, identifiers.err)
+	}
+	if policies.err != nil {
+		errs = append(errs, policies.err)
+	}
+	defer func() {
+		if len(errs) > 0 {
+			s.err = &ConfigError{Errors: errs}
+		}
+	}()
+
+	configs := []serverConfig{{
+		Name:         DefaultServer,
+		Addr:         config.Addr,
+		SocketMode:   config.SocketMode,
+		ServeHTTP:    config.ServeHTTP,
+		DrainTimeout: config.DrainTimeout,
+		TLS:          config.TLS,
+		Server:       config.Options,
+		Health:       config.Health,
+		Reflection:   config.Reflection,
+	}}
+
+	var named []serverConfig
+	if err := config.Servers.MapInto(&named); err != nil {
+		errs = append(errs, fmt.Errorf("grpc.servers: %w", err))
+	}
+	configs = append(configs, named...)
+
+	known := make(map[string]bool, len(identifiers.identifiers))
+	for _, identifier := range identifiers.identifiers {
+		known[identifier.Identifier()] = true
+	}
+
+	byName := make(map[string]*grpcServer, len(configs))
+	httpServers := 0
+	for _, cfg := range configs {
+		if _, exists := byName[cfg.Name]; exists {
+			errs = append(errs, fmt.Errorf("grpc server %q is configured more than once", cfg.Name))
+			continue
+		}
+
+		server := &grpcServer{
+			config:      cfg,
+			eventRouter: eventRouter,
+			logger:      s.logger.WithField("server", cfg.Name),
+			identity:    identityService,
+			status:      config.Status,
+			unary:       config.UnaryInterceptors,
+			stream:      config.StreamInterceptors,
+		}
+		for _, err := range server.validate(known) {
+			errs = append(errs, fmt.Errorf("grpc server %q: %w", cfg.Name, err))
+		}
+		if cfg.ServeHTTP {
+			httpServers++
+		}
+
+		byName[cfg.Name] = server
+		s.servers = append(s.servers, server)
+	}
+
+	if httpServers > 1 {
+		errs = append(errs, errors.New("only one grpc server can be served on the flamingo http server"))
+	}
+
+	byName[DefaultServer].register = register
+
+	for _, namedRegister := range config.NamedRegister {
+		server, ok := byName[namedRegister.Server]
+		if !ok {
+			errs = append(errs, fmt.Errorf("services bound to unknown grpc server %q", namedRegister.Server))
+			continue
+		}
+		server.register = append(server.register, namedRegister.Register)
+	}
+
+	for _, listener := range config.Listeners {
+		server, ok := byName[listener.Server]
+		if !ok {
+			errs = append(errs, fmt.Errorf("listener bound to unknown grpc server %q", listener.Server))
+			continue
+		}
+		server.listener = listener.Listener
+	}
+}
+
+// fail reports a server which can not be started. Unless exitOnStartupFailure is disabled the application is shut down
+// orderly, "grpc serve" returns the error instead.
+func (s *grpcServers) fail(ctx context.Context, server string, err error) {
+	s.logger.WithField("server", server).Error(fmt.Sprintf("grpc server startup failed: %v", err))
+	s.eventRouter.Dispatch(ctx, &GrpcServerStartupFailedEvent{Server: server, Err: err})
+
+	s.mu.RLock()
+	failures := s.failures
+	s.mu.RUnlock()
+
+	if failures != nil {
+		if server != "" {
+			err = fmt.Errorf("grpc server %q: %w", server, err)
+		}
+		select {
+		case failures <- err:
+		default:
+		}
+		return
+	}
+
+	if !s.exitOnFailure {
+		return
+	}
+
+	s.shutdownOnce.Do(func() {
+		s.logger.Info("shutting down after grpc server startup failure")
+		if err := requestShutdown(); err != nil {
+			s.logger.Error(fmt.Sprintf("unable to shut down: %v", err))
+		}
+	})
+}
+
+// requestShutdown sends SIGTERM to the own process, so flamingo runs its regular shutdown as on a termination signal
+func requestShutdown() error {
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		return err
+	}
+	return process.Signal(syscall.SIGTERM)
+}
+
+func (s *grpcServers) Notify(ctx context.Context, event flamingo.Event) {
+	switch event.(type) {
+	case *flamingo.ServerStartEvent:
+		s.start(ctx, true)
+	case *flamingo.ShutdownEvent:
+		s.mu.Lock()
+		s.http = nil
+		s.mu.Unlock()
+		s.each((*grpcServer).gracefulStop)
+	case *flamingo.ServerShutdownEvent:
+		s.each((*grpcServer).stop)
+	}
+}
+
+// start starts all servers, servers configured with serveHTTP are only started if withHTTP is set.
+// The returned channel is closed once all started servers stopped serving.
+func (s *grpcServers) start(ctx context.Context, withHTTP bool) <-chan struct{} {
+	done := make(chan struct{})
+	var running sync.WaitGroup
+	defer func() {
+		go func() {
+			running.Wait()
+			close(done)
+		}()
+	}()
+
+	s.mu.Lock()
+	started := s.started
+	s.started = true
+	s.mu.Unlock()
+	if started {
+		return done
+	}
+
+	if s.err != nil {
+		s.fail(ctx, "", s.err)
+		return done
+	}
+
+	for _, server := range s.servers {
+		server := server
+		if server.config.ServeHTTP {
+			if !withHTTP {
+				s.fail(ctx, server.config.Name, errors.New("serveHTTP requires the flamingo http server"))
+				continue
+			}
+			grpcServer, err := server.build()
+			if err != nil {
+				s.fail(ctx, server.config.Name, err)
+				continue
+			}
+			s.mu.Lock()
+			s.http = grpcServer
+			s.mu.Unlock()
+			server.logger.Info("grpc server ready to serve on the flamingo http server")
+			server.eventRouter.Dispatch(ctx, &GrpcServerStartedEvent{Server: server.config.Name, Addr: "flamingo"})
+			continue
+		}
+
+		running.Add(1)
+		go func() {
+			defer running.Done()
+
+			var err error
+			if server.listener != nil {
+				err = server.Serve(context.Background(), server.listener)
+			} else {
+				err = server.ServeTcpAddr(context.Background(), server.config.Addr)
+			}
+			if err != nil {
+				s.fail(context.Background(), server.config.Name, err)
+			}
+		}()
+	}
+
+	return done
+}
+
+// each runs f for all servers in parallel and waits until all are done
+func (s *grpcServers) each(f func(server *grpcServer)) {
+	var wg sync.WaitGroup
+	for _, server := range s.servers {
+		wg.Add(1)
+		go func(server *grpcServer) {
+			defer wg.Done()
+			f(server)
+		}(server)
+	}
+	wg.Wait()
+}
+
+// gracefulStop reports NOT_SERVING for the shutdown delay, then waits for running calls to finish.
+// After the drain timeout the remaining calls are aborted.
+func (s *grpcServer) gracefulStop() {
+	s.mu.Lock()
+	if s.draining != nil {
+		draining := s.draining
+		s.mu.Unlock()
+		<-draining
+		return
+	}
+	s.shutdown = true
+	s.draining = make(chan struct{})
+	defer close(s.draining)
+	grpcServer, health := s.grpcServer, s.health
+	s.mu.Unlock()
+
+	if health != nil {
+		health.shutdown()
+		if s.healthDelay > 0 {
+			time.Sleep(s.healthDelay)
+		}
+	}
+	if grpcServer != nil {
+		done := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(done)
+		}()
+
+		if s.drainTimeout > 0 {
+			timer := time.NewTimer(s.drainTimeout)
+			select {
+			case <-done:
+			case <-timer.C:
+				s.logger.Warn(fmt.Sprintf("grpc server not drained after %s, stopping", s.drainTimeout))
+				grpcServer.Stop()
+				<-done
+			}
+			timer.Stop()
+		} else {
+			<-done
+		}
+	}
+	if s.tls != nil {
+		s.tls.close()
+	}
+	s.stopped(grpcServer != nil)
+}
+
+// stop stops the server immediately, a graceful stop already in progress is awaited instead, it is bound by the drain timeout
+func (s *grpcServer) stop() {
+	s.mu.Lock()
+	draining := s.draining
+	s.shutdown = true
+	grpcServer, health := s.grpcServer, s.health
+	s.mu.Unlock()
+
+	if draining != nil {
+		<-draining
+		return
+	}
+
+	if health != nil {
+		health.shutdown()
+	}
+	if grpcServer != nil {
+		grpcServer.Stop()
+	}
+	if s.tls != nil {
+		s.tls.close()
+	}
+	s.stopped(grpcServer != nil)
+}
+
+func (s *grpcServer) stopped(started bool) {
+	if !started {
+		return
+	}
+	s.stopOnce.Do(func() {
+		s.eventRouter.Dispatch(context.Background(), &GrpcServerStoppedEvent{Server: s.config.Name})
+	})
+}
+
+// serverInfoInterceptors attach the name and the allowed identifiers of the server handling the call to the context
+func (s *grpcServer) serverInfoInterceptors() (UnaryServerInterceptor, StreamServerInterceptor) {
+	info := &serverInfo{
+		name:        s.config.Name,
+		identifiers: s.config.Identifiers,
+	}
+
+	unary := UnaryServerInterceptor{
+		Order: math.MinInt32,
+		Interceptor: func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			return handler(context.WithValue(ctx, serverInfoKey{}, info), req)
+		},
+	}
+
+	stream := StreamServerInterceptor{
+		Order: math.MinInt32,
+		Interceptor: func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			return handler(srv, &contextServerStream{
+				ServerStream: ss,
+				ctx:          context.WithValue(ss.Context(), serverInfoKey{}, info),
+			})
+		},
+	}
+
+	return unary, stream
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// ServerNameFromContext returns the name of the grpc server handling the current call
+func ServerNameFromContext(ctx context.Context) string {
+	if info, ok := ctx.Value(serverInfoKey{}).(*serverInfo); ok {
+		return info.name
+	}
+	return ""
+}
+
+// httpServer returns the grpc server served on the flamingo http server, if any
+func (s *grpcServers) httpServer() *grpc.Server {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.http
+}
+
+// ServeTcpAddr serves on the given address, besides tcp addresses unix: and systemd: addresses are supported
+func (s *grpcServer) ServeTcpAddr(ctx context.Context, addr string) error {
+	listener, err := listen(addr, s.config.SocketMode)
+	if err != nil {
+		return fmt.Errorf("unable to listen: %w", err)
+	}
+
+	return s.Serve(ctx, listener)
+}
+
+// Serve serves on the given listener, a server stopped before it was built does not serve
+func (s *grpcServer) Serve(ctx context.Context, listener net.Listener) error {
+	grpcServer, err := s.build()
+	if err != nil {
+		_ = listener.Close()
+		if errors.Is(err, errServerStopped) {
+			return nil
+		}
+		return err
+	}
+
+	s.logger.Info(fmt.Sprintf("grpc server ready to listen on %s", listener.Addr()))
+	s.eventRouter.Dispatch(ctx, &GrpcServerStartedEvent{Server: s.config.Name, Addr: listener.Addr().String()})
+
+	return grpcServer.Serve(listener)
+}
+
+// validate parses the server configuration and reports all problems, the parsed values are used by build
+func (s *grpcServer) validate(identifiers map[string]bool) []error {
+	var errs []error
+	var err error
+
+	s.identifiers = identifiers
+	if s.drainTimeout, err = duration("drainTimeout", s.config.DrainTimeout); err != nil {
+		errs = append(errs, err)
+	}
+
+	if s.options, err = serverOptions(s.config.Server); err != nil {
+		errs = append(errs, fmt.Errorf("server: %w", err))
+	}
+
+	if err := s.config.Reflection.MapInto(&s.reflection); err != nil {
+		errs = append(errs, fmt.Errorf("reflection: %w", err))
+	} else if s.reflection.Identifier != "" && !identifiers[s.reflection.Identifier] {
+		errs = append(errs, fmt.Errorf("reflection: unknown identifier %q", s.reflection.Identifier))
+	} else if s.reflection.Identifier != "" && len(s.config.Identifiers) > 0 && !contains(s.config.Identifiers, s.reflection.Identifier) {
+		errs = append(errs, fmt.Errorf("reflection: identifier %q is not in the identifiers of the server", s.reflection.Identifier))
+	}
+
+	for _, identifier := range s.config.Identifiers {
+		if !identifiers[identifier] {
+			errs = append(errs, fmt.Errorf("identifiers: unknown identifier %q", identifier))
+		}
+	}
+
+	var healthCfg healthConfig
+	if err := s.config.Health.MapInto(&healthCfg); err != nil {
+		errs = append(errs, fmt.Errorf("health: %w", err))
+	} else if healthCfg.Enabled {
+		if s.healthInterval, err = duration("health.interval", healthCfg.Interval); err != nil {
+			errs = append(errs, err)
+		} else if s.healthInterval <= 0 {
+			s.healthInterval = 10 * time.Second
+		}
+		if s.healthDelay, err = duration("health.shutdownDelay", healthCfg.ShutdownDelay); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if s.config.ServeHTTP {
+		// tls is terminated by the flamingo http server
+		if enabled, _ := s.config.TLS["enabled"].(bool); enabled {
+			errs = append(errs, errors.New("tls: can not be enabled for a server with serveHTTP"))
+		}
+	} else if s.tls, err = newCertReloader(s.config.TLS, s.logger); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// build creates the grpc server with all options, interceptors and services.
+// It returns errServerStopped if the server was stopped in the meantime.
+func (s *grpcServer) build() (*grpc.Server, error) {
+	infoUnary, infoStream := s.serverInfoInterceptors()
+	unary := append([]UnaryServerInterceptor{infoUnary}, s.unary...)
+	stream := append([]StreamServerInterceptor{infoStream}, s.stream...)
+	if s.reflection.Enabled && s.reflection.Identifier != "" {
+		stream = append(stream, reflectionGuard(s.identity, s.reflection.Identifier))
+	}
+
+	options := []grpc.ServerOption{
+		grpc.StatsHandler(&ocgrpc.ServerHandler{
+			IsPublicEndpoint: false,
+			StartOptions: trace.StartOptions{
+				SpanKind: trace.SpanKindServer,
+				Sampler:  trace.AlwaysSample(),
+			},
+		}),
+		chainUnaryInterceptors(unary),
+		chainStreamInterceptors(stream),
+	}
+	options = append(options, s.options...)
+
+	if s.tls != nil {
+		options = append(options, grpc.Creds(s.tls.credentials()))
+	}
+
+	grpcServer := grpc.NewServer(options...)
+
+	for _, rf := range s.register {
+		rf(grpcServer)
+	}
+
+	if errs := checkAnnotationIdentifiers(grpcServer.GetServiceInfo(), s.identifiers, s.config.Identifiers); len(errs) > 0 {
+		return nil, &ConfigError{Errors: errs}
+	}
+
+	if s.reflection.Enabled {
+		registerReflection(grpcServer)
+	}
+
+	var health *healthService
+	if s.healthInterval > 0 {
+		health = newHealthService(s.status, s.healthInterval, s.logger)
+		health.register(grpcServer)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.shutdown {
+		return nil, errServerStopped
+	}
+	s.grpcServer = grpcServer
+	s.health = health
+
+	if s.tls != nil {
+		go s.tls.watch()
+	}
+	if health != nil {
+		go health.run()
+	}
+
+	return grpcServer, nil
+}
@@ -0,0 +1,272 @@
+package grpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"google.golang.org/grpc/credentials"
+)
+
+type tlsConfig struct {
+	Enabled        bool     `json:"enabled"`
+	CertFile       string   `json:"certFile"`
+	KeyFile        string   `json:"keyFile"`
+	ClientCAFile   string   `json:"clientCAFile"`
+	ClientAuth     string   `json:"clientAuth"`
+	MinVersion     string   `json:"minVersion"`
+	CipherSuites   []string `json:"cipherSuites"`
+	ReloadInterval string   `json:"reloadInterval"`
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var tlsClientAuth = map[string]tls.ClientAuthType{
+	"none":               tls.NoClientCert,
+	"request":            tls.RequestClientCert,
+	"require":            tls.RequireAnyClientCert,
+	"verify":             tls.VerifyClientCertIfGiven,
+	"require-and-verify": tls.RequireAndVerifyClientCert,
+}
+
+// certReloader keeps the server certificate and client CA pool in sync with the files on disk
+type certReloader struct {
+	config   tlsConfig
+	base     *tls.Config
+	interval time.Duration
+	logger   flamingo.Logger
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	modTimes map[string]time.Time
+
+	stop   chan struct{}
+	closed bool
+}
+
+func newCertReloader(cfg config.Map, logger flamingo.Logger) (*certReloader, error) {
+	var tlsCfg tlsConfig
+	if err := cfg.MapInto(&tlsCfg); err != nil {
+		return nil, err
+	}
+
+	if !tlsCfg.Enabled {
+		return nil, nil
+	}
+
+	if tlsCfg.CertFile == "" || tlsCfg.KeyFile == "" {
+		return nil, errors.New("tls: certFile and keyFile are required")
+	}
+
+	base := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2"},
+	}
+
+	if tlsCfg.MinVersion != "" {
+		version, ok := tlsVersions[tlsCfg.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("tls: unknown minVersion %q", tlsCfg.MinVersion)
+		}
+		base.MinVersion = version
+	}
+
+	if tlsCfg.ClientAuth != "" {
+		clientAuth, ok := tlsClientAuth[tlsCfg.ClientAuth]
+		if !ok {
+			return nil, fmt.Errorf("tls: unknown clientAuth %q", tlsCfg.ClientAuth)
+		}
+		base.ClientAuth = clientAuth
+	}
+
+	if base.ClientAuth >= tls.VerifyClientCertIfGiven && tlsCfg.ClientCAFile == "" {
+		return nil, fmt.Errorf("tls: clientAuth %q requires a clientCAFile", tlsCfg.ClientAuth)
+	}
+
+	suites, err := cipherSuites(tlsCfg.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+	base.CipherSuites = suites
+
+	interval := time.Minute
+	if tlsCfg.ReloadInterval != "" {
+		interval, err = time.ParseDuration(tlsCfg.ReloadInterval)
+		if err != nil {
+			return nil, fmt.Errorf("tls: invalid reloadInterval: %w", err)
+		}
+	}
+
+	reloader := &certReloader{
+		config:   tlsCfg,
+		base:     base,
+		interval: interval,
+		logger:   logger,
+		modTimes: make(map[string]time.Time),
+	}
+
+	if err := reloader.load(); err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+func cipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, len(names))
+	for i, name := range names {
+		id, ok := known[strings.ToUpper(name)]
+		if !ok {
+			return nil, fmt.Errorf("tls: unknown cipher suite %q", name)
+		}
+		ids[i] = id
+	}
+
+	return ids, nil
+}
+
+func (r *certReloader) files() []string {
+	files := []string{r.config.CertFile, r.config.KeyFile}
+	if r.config.ClientCAFile != "" {
+		files = append(files, r.config.ClientCAFile)
+	}
+	return files
+}
+
+// changed reports whether any of the watched files has a different modification time than at the last load
+func (r *certReloader) changed() bool {
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		if !info.ModTime().Equal(r.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *certReloader) load() error {
+	modTimes := make(map[string]time.Time)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("tls: unable to load key pair: %w", err)
+	}
+
+	var pool *x509.CertPool
+	if r.config.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(r.config.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("tls: unable to read client CA bundle: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("tls: no certificates found in %s", r.config.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCA = pool
+	r.modTimes = modTimes
+	r.mu.Unlock()
+
+	return nil
+}
+
+// watch polls the certificate files and reloads them after a rotation, until close is called
+func (r *certReloader) watch() {
+	r.mu.Lock()
+	if r.stop != nil || r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.stop = make(chan struct{})
+	stop := r.stop
+	r.mu.Unlock()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.mu.RLock()
+			changed := r.changed()
+			r.mu.RUnlock()
+			if !changed {
+				continue
+			}
+			if err := r.load(); err != nil {
+				r.logger.Error(fmt.Sprintf("keeping current certificate, reload failed: %v", err))
+				continue
+			}
+			r.logger.Info(fmt.Sprintf("reloaded tls certificate %s", r.config.CertFile))
+		}
+	}
+}
+
+func (r *certReloader) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+}
+
+func (r *certReloader) tlsConfig() *tls.Config {
+	cfg := r.base.Clone()
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+
+		current := r.base.Clone()
+		current.Certificates = []tls.Certificate{*r.cert}
+		current.ClientCAs = r.clientCA
+		return current, nil
+	}
+	return cfg
+}
+
+func (r *certReloader) credentials() credentials.TransportCredentials {
+	return credentials.NewTLS(r.tlsConfig())
+}
@@ -0,0 +1,210 @@
+package grpc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+)
+
+func writeKeyPair(t *testing.T, certFile, keyFile, commonName string, modTime time.Time) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), modTime)
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), modTime)
+}
+
+func writeFile(t *testing.T, file string, content []byte, modTime time.Time) {
+	t.Helper()
+
+	if err := ioutil.WriteFile(file, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	// the modification time is set explicitly, a rotation within the file system's timestamp granularity would go unnoticed
+	if err := os.Chtimes(file, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func servedCommonName(t *testing.T, reloader *certReloader) string {
+	t.Helper()
+
+	cfg, err := reloader.tlsConfig().GetConfigForClient(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Certificates) != 1 {
+		t.Fatalf("got %d certificates, want 1", len(cfg.Certificates))
+	}
+	cert, err := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert.Subject.CommonName
+}
+
+func TestNewCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeKeyPair(t, certFile, keyFile, "server", time.Now())
+
+	tests := []struct {
+		name    string
+		cfg     config.Map
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "disabled", cfg: config.Map{"enabled": false}, wantNil: true},
+		{name: "enabled", cfg: config.Map{"enabled": true, "certFile": certFile, "keyFile": keyFile}},
+		{name: "missing key file", cfg: config.Map{"enabled": true, "certFile": certFile}, wantErr: true},
+		{name: "key pair not found", cfg: config.Map{"enabled": true, "certFile": certFile, "keyFile": filepath.Join(dir, "missing.key")}, wantErr: true},
+		{name: "unknown min version", cfg: config.Map{"enabled": true, "certFile": certFile, "keyFile": keyFile, "minVersion": "1.4"}, wantErr: true},
+		{name: "verify without client ca", cfg: config.Map{"enabled": true, "certFile": certFile, "keyFile": keyFile, "clientAuth": "require-and-verify"}, wantErr: true},
+		{name: "unknown cipher suite", cfg: config.Map{"enabled": true, "certFile": certFile, "keyFile": keyFile, "cipherSuites": []interface{}{"TLS_NULL"}}, wantErr: true},
+		{name: "invalid reload interval", cfg: config.Map{"enabled": true, "certFile": certFile, "keyFile": keyFile, "reloadInterval": "soon"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reloader, err := newCertReloader(tt.cfg, flamingo.NullLogger{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newCertReloader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (reloader == nil) != tt.wantNil {
+				t.Errorf("newCertReloader() = %v, wantNil %v", reloader, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestCertReloader_rotation(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	writeKeyPair(t, certFile, keyFile, "first", modTime)
+
+	reloader, err := newCertReloader(config.Map{"enabled": true, "certFile": certFile, "keyFile": keyFile}, flamingo.NullLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := servedCommonName(t, reloader); got != "first" {
+		t.Fatalf("served certificate = %q, want %q", got, "first")
+	}
+	if reloader.changed() {
+		t.Fatal("changed() = true before the rotation")
+	}
+
+	// a config handed out before the rotation serves the rotated certificate to new handshakes
+	established := reloader.tlsConfig()
+
+	modTime = modTime.Add(time.Minute)
+	writeKeyPair(t, certFile, keyFile, "second", modTime)
+	if !reloader.changed() {
+		t.Fatal("changed() = false after the rotation")
+	}
+	if err := reloader.load(); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if reloader.changed() {
+		t.Fatal("changed() = true after the reload")
+	}
+	if got := servedCommonName(t, reloader); got != "second" {
+		t.Errorf("served certificate = %q, want %q", got, "second")
+	}
+	cfg, err := established.GetConfigForClient(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert, _ := x509.ParseCertificate(cfg.Certificates[0].Certificate[0]); cert.Subject.CommonName != "second" {
+		t.Errorf("served certificate of the established config = %q, want %q", cert.Subject.CommonName, "second")
+	}
+
+	// a half written rotation must not replace the working certificate
+	modTime = modTime.Add(time.Minute)
+	writeFile(t, keyFile, []byte("not a key"), modTime)
+	if !reloader.changed() {
+		t.Fatal("changed() = false after the broken rotation")
+	}
+	if err := reloader.load(); err == nil {
+		t.Fatal("load() of a broken key pair succeeded")
+	}
+	if got := servedCommonName(t, reloader); got != "second" {
+		t.Errorf("served certificate after failed reload = %q, want %q", got, "second")
+	}
+	if !reloader.changed() {
+		t.Error("changed() = false after the failed reload, the rotation would not be retried")
+	}
+
+	modTime = modTime.Add(time.Minute)
+	writeKeyPair(t, certFile, keyFile, "third", modTime)
+	if err := reloader.load(); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if got := servedCommonName(t, reloader); got != "third" {
+		t.Errorf("served certificate = %q, want %q", got, "third")
+	}
+}
+
+func TestCertReloader_watch(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	writeKeyPair(t, certFile, keyFile, "first", modTime)
+
+	reloader, err := newCertReloader(config.Map{"enabled": true, "certFile": certFile, "keyFile": keyFile, "reloadInterval": "5ms"}, flamingo.NullLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		reloader.watch()
+		close(done)
+	}()
+	defer func() {
+		reloader.close()
+		<-done
+	}()
+
+	writeKeyPair(t, certFile, keyFile, "second", modTime.Add(time.Minute))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for servedCommonName(t, reloader) != "second" {
+		if time.Now().After(deadline) {
+			t.Fatal("rotated certificate not served")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}